@@ -17,7 +17,13 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
 // ReconcileStrategy represent the action the deployer should take whenever a recncile event occures
@@ -31,11 +37,123 @@ const (
 	// ReconcileStrategyStrict is used to indicate that the deployer should enforce
 	// storage clsuter based on a predefined spec
 	ReconcileStrategyStrict ReconcileStrategy = "strict"
+
+	// ReconcileStrategyMerge is used to indicate that the deployer should enforce
+	// only the fields it controls and keep any other field set on the resource
+	ReconcileStrategyMerge ReconcileStrategy = "merge"
 )
 
+// ParseReconcileStrategy returns the reconcile strategy matching the given value, ignoring
+// case. An empty value is parsed as the default, strict, reconcile strategy
+func ParseReconcileStrategy(value string) (ReconcileStrategy, error) {
+	switch {
+	case value == "" || strings.EqualFold(value, string(ReconcileStrategyStrict)):
+		return ReconcileStrategyStrict, nil
+	case strings.EqualFold(value, string(ReconcileStrategyNone)):
+		return ReconcileStrategyNone, nil
+	case strings.EqualFold(value, string(ReconcileStrategyMerge)):
+		return ReconcileStrategyMerge, nil
+	default:
+		return "", fmt.Errorf("Invalid reconcile strategy value: %v", value)
+	}
+}
+
+// ComponentReconcileStrategyMap holds the reconcile strategy of each managed component
+type ComponentReconcileStrategyMap struct {
+	// +optional
+	StorageCluster ReconcileStrategy `json:"storageCluster,omitempty"`
+	// +optional
+	Prometheus ReconcileStrategy `json:"prometheus,omitempty"`
+	// +optional
+	Alertmanager ReconcileStrategy `json:"alertmanager,omitempty"`
+	// +optional
+	AlertmanagerConfig ReconcileStrategy `json:"alertmanagerConfig,omitempty"`
+	// +optional
+	NetworkPolicies ReconcileStrategy `json:"networkPolicies,omitempty"`
+	// +optional
+	CSVResources ReconcileStrategy `json:"csvResources,omitempty"`
+}
+
+// Validate checks the reconcile strategies found at the given path
+func (m *ComponentReconcileStrategyMap) Validate(path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for name, strategy := range map[string]ReconcileStrategy{
+		"storageCluster":     m.StorageCluster,
+		"prometheus":         m.Prometheus,
+		"alertmanager":       m.Alertmanager,
+		"alertmanagerConfig": m.AlertmanagerConfig,
+		"networkPolicies":    m.NetworkPolicies,
+		"csvResources":       m.CSVResources,
+	} {
+		if _, err := ParseReconcileStrategy(string(strategy)); err != nil {
+			errs = append(errs, field.Invalid(path.Child(name), strategy, err.Error()))
+		}
+	}
+	return errs
+}
+
+// AlertMatcher selects alerts by the value of one of their labels
+type AlertMatcher struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Regex indicates whether the value is a regular expression
+	// +optional
+	Regex bool `json:"regex,omitempty"`
+}
+
+// MaintenanceWindow is a period of planned work in which alerts are not sent
+type MaintenanceWindow struct {
+	Start metav1.Time `json:"start"`
+	End   metav1.Time `json:"end"`
+
+	// Matchers select the alerts that are silenced during the window, an alert must match
+	// all of them. All the alerts are silenced when no matchers are given.
+	// +optional
+	Matchers []AlertMatcher `json:"matchers,omitempty"`
+
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// IsActive returns true if the given time is within the maintenance window
+func (w *MaintenanceWindow) IsActive(now time.Time) bool {
+	return !now.Before(w.Start.Time) && now.Before(w.End.Time)
+}
+
+// Validate checks the maintenance window found at the given path
+func (w *MaintenanceWindow) Validate(path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if !w.End.After(w.Start.Time) {
+		errs = append(errs, field.Invalid(path.Child("end"), w.End, "must be after the start of the window"))
+	}
+	for i, matcher := range w.Matchers {
+		matcherPath := path.Child("matchers").Index(i)
+		if matcher.Name == "" {
+			errs = append(errs, field.Required(matcherPath.Child("name"), "label name must be set"))
+		}
+		if matcher.Regex {
+			if _, err := regexp.Compile(matcher.Value); err != nil {
+				errs = append(errs, field.Invalid(matcherPath.Child("value"), matcher.Value, "must be a valid regular expression"))
+			}
+		}
+	}
+	return errs
+}
+
 // ManagedOCSSpec defines the desired state of ManagedOCS
 type ManagedOCSSpec struct {
 	ReconcileStrategy ReconcileStrategy `json:"reconcileStrategy,omitempty"`
+
+	// ComponentReconcileStrategies overrides the reconcile strategy of individual components.
+	// Components that are not set use the strict strategy, except for the storage cluster
+	// which uses the ReconcileStrategy field.
+	// +optional
+	ComponentReconcileStrategies ComponentReconcileStrategyMap `json:"componentReconcileStrategies,omitempty"`
+
+	// MaintenanceWindows silence alerts during planned work
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
 }
 
 type ComponentState string
@@ -57,10 +175,208 @@ type ComponentStatusMap struct {
 	Alertmanager   ComponentStatus `json:"alertmanager"`
 }
 
+type ReconcilePhaseState string
+
+const (
+	ReconcilePhaseSucceeded ReconcilePhaseState = "Succeeded"
+	ReconcilePhaseFailed    ReconcilePhaseState = "Failed"
+	ReconcilePhaseSkipped   ReconcilePhaseState = "Skipped"
+)
+
+// ReconcilePhaseStatus holds the outcome of a single reconcile phase
+type ReconcilePhaseStatus struct {
+	Name    string              `json:"name"`
+	State   ReconcilePhaseState `json:"state"`
+	Message string              `json:"message,omitempty"`
+}
+
+type DownscaleState string
+
+const (
+	DownscaleInProgress DownscaleState = "InProgress"
+	DownscaleRefused    DownscaleState = "Refused"
+	DownscaleCompleted  DownscaleState = "Completed"
+)
+
+// DownscaleStatus holds the progress of a storage device set count reduction
+type DownscaleStatus struct {
+	State          DownscaleState `json:"state"`
+	RequestedCount int            `json:"requestedCount"`
+	CurrentCount   int            `json:"currentCount"`
+	Message        string         `json:"message,omitempty"`
+
+	// LastStepTime is the time in which the device set count was last reduced
+	// +optional
+	LastStepTime *metav1.Time `json:"lastStepTime,omitempty"`
+
+	// RemovingOSDIDs lists the OSDs of the device set removed by the current step that are
+	// not purged yet. They are removed one at a time, in order. The next step starts only
+	// once Ceph no longer reports these OSDs.
+	// +optional
+	RemovingOSDIDs []int `json:"removingOSDIDs,omitempty"`
+
+	// OSDOutTime is the time in which the first OSD of RemovingOSDIDs was requested to be
+	// marked out
+	// +optional
+	OSDOutTime *metav1.Time `json:"osdOutTime,omitempty"`
+}
+
+type UninstallPhase string
+
+const (
+	UninstallRequested              UninstallPhase = "Requested"
+	UninstallBlockedOnPVCs          UninstallPhase = "BlockedOnPVCs"
+	UninstallDeletingStorageCluster UninstallPhase = "DeletingStorageCluster"
+	UninstallRemovingCSV            UninstallPhase = "RemovingCSV"
+)
+
+type BlockingResourceKind string
+
+const (
+	BlockingPersistentVolumeClaim BlockingResourceKind = "PersistentVolumeClaim"
+	BlockingPersistentVolume      BlockingResourceKind = "PersistentVolume"
+	BlockingVolumeSnapshot        BlockingResourceKind = "VolumeSnapshot"
+	BlockingObjectBucketClaim     BlockingResourceKind = "ObjectBucketClaim"
+)
+
+// BlockingResource identifies a consumer resource that prevents the uninstall
+type BlockingResource struct {
+	Kind BlockingResourceKind `json:"kind"`
+
+	// Namespace is empty for cluster scoped resources
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	Name string `json:"name"`
+
+	// Class is the storage class, or the volume snapshot class, used by the resource
+	// +optional
+	Class string `json:"class,omitempty"`
+}
+
+// ForceUninstallStatus holds the progress of an acknowledged force uninstall request
+type ForceUninstallStatus struct {
+	Reason string `json:"reason"`
+
+	// RequestedTime is the time in which the force uninstall request was accepted
+	RequestedTime metav1.Time `json:"requestedTime"`
+
+	// WarningTime is the time in which the customer warning about the deletion was
+	// observed being sent
+	// +optional
+	WarningTime *metav1.Time `json:"warningTime,omitempty"`
+
+	// DeletionTime is the time after which the resources blocking the uninstall are deleted.
+	// It is set once the customer was warned.
+	// +optional
+	DeletionTime *metav1.Time `json:"deletionTime,omitempty"`
+
+	// DeletedResourceCount is the number of blocking resources that were deleted
+	// +optional
+	DeletedResourceCount int `json:"deletedResourceCount,omitempty"`
+}
+
+// UninstallStatus holds the progress of a requested uninstall
+type UninstallStatus struct {
+	Phase   UninstallPhase `json:"phase"`
+	Message string         `json:"message,omitempty"`
+
+	// PhaseStartTime is the time in which the uninstall entered its current phase
+	PhaseStartTime metav1.Time `json:"phaseStartTime"`
+
+	// BlockingResourceCount is the number of consumer resources that prevent the uninstall
+	// +optional
+	BlockingResourceCount int `json:"blockingResourceCount,omitempty"`
+
+	// BlockingResources lists the consumer resources that prevent the uninstall, up to a
+	// limited count
+	// +optional
+	BlockingResources []BlockingResource `json:"blockingResources,omitempty"`
+
+	// LastWarningTime is the time in which the blocked uninstall was last reported as an event
+	// +optional
+	LastWarningTime *metav1.Time `json:"lastWarningTime,omitempty"`
+
+	// RejectedForceUninstallRequest is the value of the last rejected force uninstall
+	// annotation, each rejected value is reported once as an event
+	// +optional
+	RejectedForceUninstallRequest string `json:"rejectedForceUninstallRequest,omitempty"`
+
+	// Force holds the progress of the force uninstall, when one was requested
+	// +optional
+	Force *ForceUninstallStatus `json:"force,omitempty"`
+}
+
+// RejectedNotificationEmail describes a notification email add-on parameter that
+// is not used for alert notifications
+type RejectedNotificationEmail struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// Condition types reported on the ManagedOCS resource
+const (
+	// ConditionReady indicates that all the managed components are ready
+	ConditionReady string = "Ready"
+
+	// ConditionDegraded indicates that the last reconcile failed in one of its phases
+	ConditionDegraded string = "Degraded"
+
+	// ConditionProgressing indicates that one or more managed components are
+	// still converging towards their desired state
+	ConditionProgressing string = "Progressing"
+
+	// ConditionUninstallBlocked indicates that an uninstall was requested but
+	// cannot proceed
+	ConditionUninstallBlocked string = "UninstallBlocked"
+
+	// ConditionAlertingConfigured indicates whether the alerting pipeline
+	// (AlertmanagerConfig and its receivers) was configured successfully
+	ConditionAlertingConfigured string = "AlertingConfigured"
+
+	// ConditionHeartbeatHealthy indicates whether alertmanager keeps delivering
+	// the Dead Man's Snitch heartbeat
+	ConditionHeartbeatHealthy string = "HeartbeatHealthy"
+
+	// ConditionAlertReceiversValid indicates whether the secrets of all the enabled
+	// optional alert receivers are valid. Receivers with an invalid secret are left out
+	// of the alerting configuration.
+	ConditionAlertReceiversValid string = "AlertReceiversValid"
+)
+
 // ManagedOCSStatus defines the observed state of ManagedOCS
 type ManagedOCSStatus struct {
 	ReconcileStrategy ReconcileStrategy  `json:"reconcileStrategy,omitempty"`
 	Components        ComponentStatusMap `json:"components"`
+
+	// ComponentReconcileStrategies holds the effective reconcile strategy of each component
+	// +optional
+	ComponentReconcileStrategies ComponentReconcileStrategyMap `json:"componentReconcileStrategies,omitempty"`
+
+	// Conditions holds the latest observations of the ManagedOCS state
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ReconcilePhases holds the outcome of each reconcile phase from the last reconcile
+	// +optional
+	ReconcilePhases []ReconcilePhaseStatus `json:"reconcilePhases,omitempty"`
+
+	// RejectedNotificationEmails lists the notification email add-on parameters
+	// that were left out of the alert notification recipients
+	// +optional
+	RejectedNotificationEmails []RejectedNotificationEmail `json:"rejectedNotificationEmails,omitempty"`
+
+	// Downscale holds the progress of the last requested storage cluster downscale
+	// +optional
+	Downscale *DownscaleStatus `json:"downscale,omitempty"`
+
+	// ActiveMaintenanceWindows lists the maintenance windows that currently silence alerts
+	// +optional
+	ActiveMaintenanceWindows []MaintenanceWindow `json:"activeMaintenanceWindows,omitempty"`
+
+	// Uninstall holds the progress of the requested uninstall
+	// +optional
+	Uninstall *UninstallStatus `json:"uninstall,omitempty"`
 }
 
 // +kubebuilder:object:root=true
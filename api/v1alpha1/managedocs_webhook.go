@@ -0,0 +1,70 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the ManagedOCS validating webhook with the manager
+func (r *ManagedOCS) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-ocs-openshift-io-v1alpha1-managedocs,mutating=false,failurePolicy=fail,sideEffects=None,groups=ocs.openshift.io,resources=managedocs,verbs=create;update,versions=v1alpha1,name=vmanagedocs.ocs.openshift.io,admissionReviewVersions={v1,v1beta1}
+
+var _ webhook.Validator = &ManagedOCS{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *ManagedOCS) ValidateCreate() error {
+	return r.validateSpec()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *ManagedOCS) ValidateUpdate(old runtime.Object) error {
+	return r.validateSpec()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *ManagedOCS) ValidateDelete() error {
+	return nil
+}
+
+func (r *ManagedOCS) validateSpec() error {
+	errs := field.ErrorList{}
+	if _, err := ParseReconcileStrategy(string(r.Spec.ReconcileStrategy)); err != nil {
+		errs = append(errs, field.Invalid(
+			field.NewPath("spec", "reconcileStrategy"),
+			r.Spec.ReconcileStrategy,
+			err.Error(),
+		))
+	}
+	errs = append(errs, r.Spec.ComponentReconcileStrategies.Validate(field.NewPath("spec", "componentReconcileStrategies"))...)
+	for i := range r.Spec.MaintenanceWindows {
+		errs = append(errs, r.Spec.MaintenanceWindows[i].Validate(field.NewPath("spec", "maintenanceWindows").Index(i))...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("ManagedOCS").GroupKind(), r.Name, errs)
+}
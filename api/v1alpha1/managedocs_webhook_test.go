@@ -0,0 +1,71 @@
+package v1alpha1
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ManagedOCS validating webhook", func() {
+	var managedOCS *ManagedOCS
+
+	newWindow := func(start time.Time, duration time.Duration, matchers ...AlertMatcher) MaintenanceWindow {
+		return MaintenanceWindow{
+			Start:    metav1.NewTime(start),
+			End:      metav1.NewTime(start.Add(duration)),
+			Matchers: matchers,
+		}
+	}
+
+	BeforeEach(func() {
+		managedOCS = &ManagedOCS{}
+		managedOCS.Name = "managedocs"
+	})
+
+	It("should accept an empty spec", func() {
+		Expect(managedOCS.ValidateCreate()).Should(Succeed())
+		Expect(managedOCS.ValidateUpdate(&ManagedOCS{})).Should(Succeed())
+	})
+	It("should accept valid reconcile strategies and maintenance windows", func() {
+		managedOCS.Spec.ReconcileStrategy = ReconcileStrategyNone
+		managedOCS.Spec.ComponentReconcileStrategies.Prometheus = ReconcileStrategyStrict
+		managedOCS.Spec.ComponentReconcileStrategies.StorageCluster = ReconcileStrategyMerge
+		managedOCS.Spec.MaintenanceWindows = []MaintenanceWindow{
+			newWindow(time.Now(), time.Hour, AlertMatcher{Name: "alertname", Value: "Ceph.*", Regex: true}),
+		}
+		Expect(managedOCS.ValidateCreate()).Should(Succeed())
+		Expect(managedOCS.ValidateUpdate(&ManagedOCS{})).Should(Succeed())
+	})
+	It("should reject an invalid reconcile strategy", func() {
+		managedOCS.Spec.ReconcileStrategy = "sometimes"
+		err := managedOCS.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.reconcileStrategy"))
+	})
+	It("should reject an invalid component reconcile strategy on update", func() {
+		old := managedOCS.DeepCopy()
+		managedOCS.Spec.ComponentReconcileStrategies.Alertmanager = "sometimes"
+		err := managedOCS.ValidateUpdate(old)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.componentReconcileStrategies.alertmanager"))
+	})
+	It("should reject invalid maintenance windows", func() {
+		managedOCS.Spec.MaintenanceWindows = []MaintenanceWindow{
+			newWindow(time.Now(), -time.Hour),
+			newWindow(time.Now(), time.Hour, AlertMatcher{Value: "ceph"}),
+			newWindow(time.Now(), time.Hour, AlertMatcher{Name: "alertname", Value: "(", Regex: true}),
+		}
+		err := managedOCS.ValidateCreate()
+		Expect(apierrors.IsInvalid(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("spec.maintenanceWindows[0].end"))
+		Expect(err.Error()).To(ContainSubstring("spec.maintenanceWindows[1].matchers[0].name"))
+		Expect(err.Error()).To(ContainSubstring("spec.maintenanceWindows[2].matchers[0].value"))
+	})
+	It("should allow deletion", func() {
+		managedOCS.Spec.ReconcileStrategy = "sometimes"
+		Expect(managedOCS.ValidateDelete()).Should(Succeed())
+	})
+})
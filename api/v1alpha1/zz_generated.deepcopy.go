@@ -21,9 +21,55 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertMatcher) DeepCopyInto(out *AlertMatcher) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertMatcher.
+func (in *AlertMatcher) DeepCopy() *AlertMatcher {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertMatcher)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockingResource) DeepCopyInto(out *BlockingResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockingResource.
+func (in *BlockingResource) DeepCopy() *BlockingResource {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockingResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentReconcileStrategyMap) DeepCopyInto(out *ComponentReconcileStrategyMap) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentReconcileStrategyMap.
+func (in *ComponentReconcileStrategyMap) DeepCopy() *ComponentReconcileStrategyMap {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentReconcileStrategyMap)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
 	*out = *in
@@ -57,13 +103,87 @@ func (in *ComponentStatusMap) DeepCopy() *ComponentStatusMap {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DownscaleStatus) DeepCopyInto(out *DownscaleStatus) {
+	*out = *in
+	if in.LastStepTime != nil {
+		in, out := &in.LastStepTime, &out.LastStepTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RemovingOSDIDs != nil {
+		in, out := &in.RemovingOSDIDs, &out.RemovingOSDIDs
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.OSDOutTime != nil {
+		in, out := &in.OSDOutTime, &out.OSDOutTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DownscaleStatus.
+func (in *DownscaleStatus) DeepCopy() *DownscaleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DownscaleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForceUninstallStatus) DeepCopyInto(out *ForceUninstallStatus) {
+	*out = *in
+	in.RequestedTime.DeepCopyInto(&out.RequestedTime)
+	if in.WarningTime != nil {
+		in, out := &in.WarningTime, &out.WarningTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DeletionTime != nil {
+		in, out := &in.DeletionTime, &out.DeletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForceUninstallStatus.
+func (in *ForceUninstallStatus) DeepCopy() *ForceUninstallStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ForceUninstallStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+	if in.Matchers != nil {
+		in, out := &in.Matchers, &out.Matchers
+		*out = make([]AlertMatcher, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManagedOCS) DeepCopyInto(out *ManagedOCS) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedOCS.
@@ -119,6 +239,14 @@ func (in *ManagedOCSList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManagedOCSSpec) DeepCopyInto(out *ManagedOCSSpec) {
 	*out = *in
+	out.ComponentReconcileStrategies = in.ComponentReconcileStrategies
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedOCSSpec.
@@ -135,6 +263,41 @@ func (in *ManagedOCSSpec) DeepCopy() *ManagedOCSSpec {
 func (in *ManagedOCSStatus) DeepCopyInto(out *ManagedOCSStatus) {
 	*out = *in
 	out.Components = in.Components
+	out.ComponentReconcileStrategies = in.ComponentReconcileStrategies
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReconcilePhases != nil {
+		in, out := &in.ReconcilePhases, &out.ReconcilePhases
+		*out = make([]ReconcilePhaseStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.RejectedNotificationEmails != nil {
+		in, out := &in.RejectedNotificationEmails, &out.RejectedNotificationEmails
+		*out = make([]RejectedNotificationEmail, len(*in))
+		copy(*out, *in)
+	}
+	if in.Downscale != nil {
+		in, out := &in.Downscale, &out.Downscale
+		*out = new(DownscaleStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ActiveMaintenanceWindows != nil {
+		in, out := &in.ActiveMaintenanceWindows, &out.ActiveMaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Uninstall != nil {
+		in, out := &in.Uninstall, &out.Uninstall
+		*out = new(UninstallStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedOCSStatus.
@@ -146,3 +309,63 @@ func (in *ManagedOCSStatus) DeepCopy() *ManagedOCSStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReconcilePhaseStatus) DeepCopyInto(out *ReconcilePhaseStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReconcilePhaseStatus.
+func (in *ReconcilePhaseStatus) DeepCopy() *ReconcilePhaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReconcilePhaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RejectedNotificationEmail) DeepCopyInto(out *RejectedNotificationEmail) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RejectedNotificationEmail.
+func (in *RejectedNotificationEmail) DeepCopy() *RejectedNotificationEmail {
+	if in == nil {
+		return nil
+	}
+	out := new(RejectedNotificationEmail)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UninstallStatus) DeepCopyInto(out *UninstallStatus) {
+	*out = *in
+	in.PhaseStartTime.DeepCopyInto(&out.PhaseStartTime)
+	if in.BlockingResources != nil {
+		in, out := &in.BlockingResources, &out.BlockingResources
+		*out = make([]BlockingResource, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastWarningTime != nil {
+		in, out := &in.LastWarningTime, &out.LastWarningTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Force != nil {
+		in, out := &in.Force, &out.Force
+		*out = new(ForceUninstallStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UninstallStatus.
+func (in *UninstallStatus) DeepCopy() *UninstallStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UninstallStatus)
+	in.DeepCopyInto(out)
+	return out
+}
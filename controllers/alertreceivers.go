@@ -0,0 +1,196 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	opsgenieAPIKeyKey     = "API_KEY"
+	opsgenieAPIURLKey     = "API_URL"
+	defaultOpsgenieAPIURL = "https://api.opsgenie.com/"
+	slackWebhookURLKey    = "WEBHOOK_URL"
+	slackChannelKey       = "CHANNEL"
+	webhookURLKey         = "URL"
+)
+
+// optionalAlertReceiver is an alert receiver that is enabled only when its secret
+// is present in the namespace
+type optionalAlertReceiver struct {
+	receiverName string
+	secret       *corev1.Secret
+
+	// configure fills the receiver configuration from the content of the secret
+	configure func(receiver *promv1a1.Receiver, secret *corev1.Secret) error
+
+	// endpoint returns the URL the receiver sends notifications to
+	endpoint func(secret *corev1.Secret) (string, error)
+}
+
+func (r *ManagedOCSReconciler) optionalAlertReceivers() []optionalAlertReceiver {
+	return []optionalAlertReceiver{{
+		receiverName: templates.OpsgenieReceiverName,
+		secret:       r.opsgenieSecret,
+		configure:    configureOpsgenieReceiver,
+		endpoint:     getOpsgenieEndpoint,
+	}, {
+		receiverName: templates.SlackReceiverName,
+		secret:       r.slackSecret,
+		configure:    configureSlackReceiver,
+		endpoint:     getSecretValueFunc(slackWebhookURLKey),
+	}, {
+		receiverName: templates.WebhookReceiverName,
+		secret:       r.webhookSecret,
+		configure:    configureWebhookReceiver,
+		endpoint:     getSecretValueFunc(webhookURLKey),
+	}}
+}
+
+// getEnabledAlertReceivers returns the optional receivers whose secret exists and is valid.
+// Receivers with an invalid secret are skipped, so they do not prevent the required
+// receivers from being configured, and are reported in the AlertReceiversValid condition.
+func (r *ManagedOCSReconciler) getEnabledAlertReceivers() []optionalAlertReceiver {
+	enabled := []optionalAlertReceiver{}
+	invalid := []string{}
+	for _, receiver := range r.optionalAlertReceivers() {
+		if receiver.secret.UID == "" {
+			if err := r.get(receiver.secret); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				invalid = append(invalid, fmt.Sprintf("Unable to get %v secret: %v", receiver.receiverName, err))
+				continue
+			}
+		}
+		if err := validateAlertReceiver(receiver); err != nil {
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		enabled = append(enabled, receiver)
+	}
+	r.updateAlertReceiversCondition(invalid)
+	return enabled
+}
+
+// getEnabledAlertReceiverHosts returns the host names of the enabled optional receivers
+func (r *ManagedOCSReconciler) getEnabledAlertReceiverHosts() []string {
+	hosts := []string{}
+	for _, receiver := range r.getEnabledAlertReceivers() {
+		// The endpoint was checked when the receiver was enabled
+		endpoint, _ := receiver.endpoint(receiver.secret)
+		endpointURL, _ := url.Parse(endpoint)
+		hosts = append(hosts, endpointURL.Hostname())
+	}
+	return hosts
+}
+
+// validateAlertReceiver checks that the secret of the receiver holds a usable endpoint and
+// everything needed to configure the receiver
+func validateAlertReceiver(receiver optionalAlertReceiver) error {
+	endpoint, err := receiver.endpoint(receiver.secret)
+	if err != nil {
+		return err
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Hostname() == "" {
+		return fmt.Errorf("Unable to parse %v url from secret %v", receiver.receiverName, receiver.secret.Name)
+	}
+	for i := range templates.AlertmanagerConfigTemplate.Spec.Receivers {
+		template := &templates.AlertmanagerConfigTemplate.Spec.Receivers[i]
+		if template.Name == receiver.receiverName {
+			return receiver.configure(template.DeepCopy(), receiver.secret)
+		}
+	}
+	return nil
+}
+
+// updateAlertReceiversCondition reports the optional receivers that were skipped. A warning
+// event is recorded each time the set of problems changes.
+func (r *ManagedOCSReconciler) updateAlertReceiversCondition(problems []string) {
+	if len(problems) == 0 {
+		r.setCondition(v1.ConditionAlertReceiversValid, metav1.ConditionTrue, "AlertReceiversValid", "All enabled alert receivers are valid")
+		return
+	}
+	message := strings.Join(problems, "; ")
+	current := meta.FindStatusCondition(r.managedOCS.Status.Conditions, v1.ConditionAlertReceiversValid)
+	if current == nil || current.Status != metav1.ConditionFalse || current.Message != message {
+		r.recordWarning(eventReasonAlertReceiverInvalid, "Skipping invalid alert receivers: %s", message)
+	}
+	r.setCondition(v1.ConditionAlertReceiversValid, metav1.ConditionFalse, "InvalidAlertReceivers", message)
+}
+
+func configureOpsgenieReceiver(receiver *promv1a1.Receiver, secret *corev1.Secret) error {
+	if _, err := getSecretValueFunc(opsgenieAPIKeyKey)(secret); err != nil {
+		return err
+	}
+	apiURL, err := getOpsgenieEndpoint(secret)
+	if err != nil {
+		return err
+	}
+	receiver.OpsGenieConfigs[0].APIKey.LocalObjectReference.Name = secret.Name
+	receiver.OpsGenieConfigs[0].APIKey.Key = opsgenieAPIKeyKey
+	receiver.OpsGenieConfigs[0].APIURL = apiURL
+	return nil
+}
+
+func configureSlackReceiver(receiver *promv1a1.Receiver, secret *corev1.Secret) error {
+	if _, err := getSecretValueFunc(slackWebhookURLKey)(secret); err != nil {
+		return err
+	}
+	receiver.SlackConfigs[0].APIURL.LocalObjectReference.Name = secret.Name
+	receiver.SlackConfigs[0].APIURL.Key = slackWebhookURLKey
+	receiver.SlackConfigs[0].Channel = string(secret.Data[slackChannelKey])
+	return nil
+}
+
+func configureWebhookReceiver(receiver *promv1a1.Receiver, secret *corev1.Secret) error {
+	if _, err := getSecretValueFunc(webhookURLKey)(secret); err != nil {
+		return err
+	}
+	receiver.WebhookConfigs[0].URLSecret.LocalObjectReference.Name = secret.Name
+	receiver.WebhookConfigs[0].URLSecret.Key = webhookURLKey
+	return nil
+}
+
+func getOpsgenieEndpoint(secret *corev1.Secret) (string, error) {
+	if apiURL := string(secret.Data[opsgenieAPIURLKey]); apiURL != "" {
+		return apiURL, nil
+	}
+	return defaultOpsgenieAPIURL, nil
+}
+
+// getSecretValueFunc returns a function that reads a required entry from a secret
+func getSecretValueFunc(key string) func(secret *corev1.Secret) (string, error) {
+	return func(secret *corev1.Secret) (string, error) {
+		value := string(secret.Data[key])
+		if value == "" {
+			return "", fmt.Errorf("Secret %v does not contain a %v entry", secret.Name, key)
+		}
+		return value, nil
+	}
+}
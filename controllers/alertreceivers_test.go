@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Optional alert receivers", func() {
+	var r *ManagedOCSReconciler
+
+	// Secrets with a UID are considered fetched, the receivers are evaluated without a client
+	newSecret := func(name string, data map[string]string) *corev1.Secret {
+		secret := &corev1.Secret{}
+		secret.Name = name
+		secret.UID = types.UID(name + "-uid")
+		secret.Data = map[string][]byte{}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return secret
+	}
+
+	getEnabledReceiverNames := func() []string {
+		names := []string{}
+		for _, receiver := range r.getEnabledAlertReceivers() {
+			names = append(names, receiver.receiverName)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		r = newTestReconciler(utils.NewDefaultAddonParams())
+		r.opsgenieSecret = newSecret("opsgenie", map[string]string{opsgenieAPIKeyKey: "key"})
+		r.slackSecret = newSecret("slack", map[string]string{slackWebhookURLKey: "https://hooks.slack.test/services/T0"})
+		r.webhookSecret = newSecret("webhook", map[string]string{webhookURLKey: "https://webhook.test/alerts"})
+	})
+
+	It("should enable all the receivers with a valid secret", func() {
+		Expect(getEnabledReceiverNames()).To(ConsistOf(
+			templates.OpsgenieReceiverName,
+			templates.SlackReceiverName,
+			templates.WebhookReceiverName,
+		))
+		Expect(r.getEnabledAlertReceiverHosts()).To(ConsistOf("api.opsgenie.com", "hooks.slack.test", "webhook.test"))
+		Expect(meta.IsStatusConditionTrue(r.managedOCS.Status.Conditions, v1.ConditionAlertReceiversValid)).To(BeTrue())
+	})
+	It("should skip and report the receivers with an invalid secret", func() {
+		r.slackSecret.Data = map[string][]byte{}
+		r.webhookSecret.Data[webhookURLKey] = []byte("not a url")
+		recorder := r.Recorder.(*record.FakeRecorder)
+
+		Expect(getEnabledReceiverNames()).To(ConsistOf(templates.OpsgenieReceiverName))
+		Expect(r.getEnabledAlertReceiverHosts()).To(ConsistOf("api.opsgenie.com"))
+
+		condition := meta.FindStatusCondition(r.managedOCS.Status.Conditions, v1.ConditionAlertReceiversValid)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition.Message).To(ContainSubstring("slack"))
+		Expect(condition.Message).To(ContainSubstring("webhook"))
+
+		// The same problems are only reported once
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(<-recorder.Events).To(ContainSubstring(eventReasonAlertReceiverInvalid))
+	})
+	It("should report the receivers as valid again once their secret is fixed", func() {
+		r.slackSecret.Data = map[string][]byte{}
+		Expect(getEnabledReceiverNames()).ToNot(ContainElement(templates.SlackReceiverName))
+
+		r.slackSecret.Data[slackWebhookURLKey] = []byte("https://hooks.slack.test/services/T0")
+		Expect(getEnabledReceiverNames()).To(ContainElement(templates.SlackReceiverName))
+		Expect(meta.IsStatusConditionTrue(r.managedOCS.Status.Conditions, v1.ConditionAlertReceiversValid)).To(BeTrue())
+	})
+})
@@ -0,0 +1,182 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	prometheusServiceURLFormat = "http://prometheus-operated.%s.svc:9090"
+	prometheusQueryTimeout     = 10 * time.Second
+
+	cephUsedBytesQuery    = "sum(ceph_cluster_total_used_raw_bytes)"
+	cephTotalBytesQuery   = "sum(ceph_cluster_total_bytes)"
+	cephPGsNotActiveQuery = "sum(ceph_pg_total) - sum(ceph_pg_active)"
+	cephPGsNotCleanQuery  = "sum(ceph_pg_total) - sum(ceph_pg_clean)"
+	cephOSDUpQuery        = "ceph_osd_up"
+
+	cephDaemonLabelKey  = "ceph_daemon"
+	cephOSDDaemonPrefix = "osd."
+)
+
+// cephMetricsProvider exposes the Ceph cluster state needed for safely reducing
+// the storage capacity
+type cephMetricsProvider interface {
+	// getCapacity returns the used and total raw capacity of the Ceph cluster in bytes
+	getCapacity(ctx context.Context) (used float64, total float64, err error)
+
+	// arePGsActiveClean returns true when all placement groups are active+clean
+	arePGsActiveClean(ctx context.Context) (bool, error)
+
+	// getOSDUpStates returns whether each OSD known to Ceph is up, keyed by the OSD ID
+	getOSDUpStates(ctx context.Context) (map[int]bool, error)
+}
+
+// prometheusCephMetrics reads Ceph metrics from the managed Prometheus instance
+type prometheusCephMetrics struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+func newPrometheusCephMetrics(namespace string) *prometheusCephMetrics {
+	return &prometheusCephMetrics{
+		endpoint:   fmt.Sprintf(prometheusServiceURLFormat, namespace),
+		httpClient: &http.Client{Timeout: prometheusQueryTimeout},
+	}
+}
+
+func (p *prometheusCephMetrics) getCapacity(ctx context.Context) (float64, float64, error) {
+	used, err := p.query(ctx, cephUsedBytesQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+	total, err := p.query(ctx, cephTotalBytesQuery)
+	if err != nil {
+		return 0, 0, err
+	}
+	if total <= 0 {
+		return 0, 0, fmt.Errorf("Ceph reported a total capacity of %v bytes", total)
+	}
+	return used, total, nil
+}
+
+func (p *prometheusCephMetrics) arePGsActiveClean(ctx context.Context) (bool, error) {
+	for _, query := range []string{cephPGsNotActiveQuery, cephPGsNotCleanQuery} {
+		count, err := p.query(ctx, query)
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (p *prometheusCephMetrics) getOSDUpStates(ctx context.Context) (map[int]bool, error) {
+	samples, err := p.queryVector(ctx, cephOSDUpQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("prometheus query %q returned no data", cephOSDUpQuery)
+	}
+	states := map[int]bool{}
+	for _, sample := range samples {
+		daemon := sample.labels[cephDaemonLabelKey]
+		id, err := strconv.Atoi(strings.TrimPrefix(daemon, cephOSDDaemonPrefix))
+		if err != nil || !strings.HasPrefix(daemon, cephOSDDaemonPrefix) {
+			return nil, fmt.Errorf("prometheus query %q returned an invalid OSD daemon %q", cephOSDUpQuery, daemon)
+		}
+		states[id] = sample.value == 1
+	}
+	return states, nil
+}
+
+// query runs an instant query that is expected to return a single sample
+func (p *prometheusCephMetrics) query(ctx context.Context, query string) (float64, error) {
+	samples, err := p.queryVector(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	if len(samples) != 1 {
+		return 0, fmt.Errorf("prometheus query %q returned no data", query)
+	}
+	return samples[0].value, nil
+}
+
+type prometheusSample struct {
+	labels map[string]string
+	value  float64
+}
+
+// queryVector runs an instant query and returns all the samples of the result
+func (p *prometheusCephMetrics) queryVector(ctx context.Context, query string) ([]prometheusSample, error) {
+	queryURL := fmt.Sprintf("%s/api/v1/query?%s", p.endpoint, url.Values{"query": {query}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, queryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query prometheus: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("prometheus query %q failed with status %s", query, resp.Status)
+	}
+
+	result := struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("unable to decode prometheus response: %v", err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("prometheus query %q failed with status %q", query, result.Status)
+	}
+	samples := []prometheusSample{}
+	for _, item := range result.Data.Result {
+		if len(item.Value) != 2 {
+			return nil, fmt.Errorf("prometheus query %q returned an invalid sample", query)
+		}
+		valueAsString, ok := item.Value[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("prometheus query %q returned an invalid value", query)
+		}
+		value, err := strconv.ParseFloat(valueAsString, 64)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus query %q returned an invalid value: %v", query, err)
+		}
+		samples = append(samples, prometheusSample{labels: item.Metric, value: value})
+	}
+	return samples, nil
+}
@@ -0,0 +1,95 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type cloudProvider string
+
+const (
+	cloudProviderAWS     cloudProvider = "aws"
+	cloudProviderGCP     cloudProvider = "gce"
+	cloudProviderAzure   cloudProvider = "azure"
+	cloudProviderUnknown cloudProvider = ""
+
+	workerNodeLabelKey = "node-role.kubernetes.io/worker"
+)
+
+// defaultStorageClassNames maps a cloud provider to the storage class used for the
+// mon and OSD PVCs when the add-on parameters do not specify one
+var defaultStorageClassNames = map[cloudProvider]string{
+	cloudProviderAWS:   "gp2",
+	cloudProviderGCP:   "pd-ssd",
+	cloudProviderAzure: "managed-premium",
+}
+
+// getCloudProvider returns the cloud provider hosting the cluster. The provider is
+// detected from the provider ID of the worker nodes and cached on the reconciler,
+// a cluster does not move between providers.
+func (r *ManagedOCSReconciler) getCloudProvider() (cloudProvider, error) {
+	if r.cloudProvider != nil {
+		return *r.cloudProvider, nil
+	}
+
+	nodeList := corev1.NodeList{}
+	if err := r.UnrestrictedClient.List(r.ctx, &nodeList, client.HasLabels{workerNodeLabelKey}); err != nil {
+		return cloudProviderUnknown, fmt.Errorf("unable to list nodes: %v", err)
+	}
+
+	provider := cloudProviderUnknown
+	for i := range nodeList.Items {
+		providerID := nodeList.Items[i].Spec.ProviderID
+		if index := strings.Index(providerID, "://"); index > 0 {
+			provider = cloudProvider(providerID[:index])
+			break
+		}
+	}
+
+	// Nodes might not have been initialized by the cloud controller yet, avoid
+	// caching the result until the provider is known
+	if provider != cloudProviderUnknown {
+		r.Log.Info("Detected cloud provider", "provider", provider)
+		r.cloudProvider = &provider
+	}
+	return provider, nil
+}
+
+// getDefaultStorageClassName returns the default storage class of the cloud provider. As the
+// storage class of a device set cannot be changed once it is created, an error is returned
+// while the provider is not known yet or is not supported, instead of guessing a storage
+// class that might not exist.
+func (r *ManagedOCSReconciler) getDefaultStorageClassName() (string, error) {
+	provider, err := r.getCloudProvider()
+	if err != nil {
+		return "", err
+	}
+	if provider == cloudProviderUnknown {
+		return "", fmt.Errorf("Unable to detect the cloud provider, the %s add-on parameter must be set until the worker nodes report their provider ID", utils.StorageClassNameKey)
+	}
+	storageClassName, found := defaultStorageClassNames[provider]
+	if !found {
+		return "", fmt.Errorf("Cloud provider %q has no default storage class, the %s add-on parameter must be set", provider, utils.StorageClassNameKey)
+	}
+	return storageClassName, nil
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+)
+
+var _ = Describe("Cloud provider defaults", func() {
+	newReconcilerOnProvider := func(provider cloudProvider) *ManagedOCSReconciler {
+		r := newTestReconciler(utils.NewDefaultAddonParams())
+		r.cloudProvider = &provider
+		return r
+	}
+
+	It("should use the default storage class of each supported provider", func() {
+		for provider, storageClassName := range map[cloudProvider]string{
+			cloudProviderAWS:   "gp2",
+			cloudProviderGCP:   "pd-ssd",
+			cloudProviderAzure: "managed-premium",
+		} {
+			name, err := newReconcilerOnProvider(provider).getDefaultStorageClassName()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal(storageClassName))
+		}
+	})
+	It("should not default a storage class while the provider is unknown", func() {
+		_, err := newReconcilerOnProvider(cloudProviderUnknown).getDefaultStorageClassName()
+		Expect(err).To(HaveOccurred())
+	})
+	It("should not default a storage class for an unsupported provider", func() {
+		_, err := newReconcilerOnProvider(cloudProvider("openstack")).getDefaultStorageClassName()
+		Expect(err).To(HaveOccurred())
+	})
+	It("should keep the storage class of the add-on parameters regardless of the provider", func() {
+		r := newReconcilerOnProvider(cloudProviderUnknown)
+		r.addonParams.StorageClassName = "test-storage-class"
+		sc := templates.StorageClusterTemplate.DeepCopy()
+		Expect(r.setDeviceSetStorageParams(sc, &sc.Spec.StorageDeviceSets[0], nil)).Should(Succeed())
+		Expect(*sc.Spec.StorageDeviceSets[0].DataPVCTemplate.Spec.StorageClassName).To(Equal("test-storage-class"))
+	})
+	It("should keep the storage class of an existing device set while the provider is unknown", func() {
+		r := newReconcilerOnProvider(cloudProviderUnknown)
+		currStorageClassName := "test-storage-class"
+		currDeviceSet := templates.StorageClusterTemplate.Spec.StorageDeviceSets[0].DeepCopy()
+		currDeviceSet.DataPVCTemplate.Spec.StorageClassName = &currStorageClassName
+
+		sc := templates.StorageClusterTemplate.DeepCopy()
+		Expect(r.setDeviceSetStorageParams(sc, &sc.Spec.StorageDeviceSets[0], currDeviceSet)).Should(Succeed())
+		Expect(*sc.Spec.StorageDeviceSets[0].DataPVCTemplate.Spec.StorageClassName).To(Equal("test-storage-class"))
+		Expect(*sc.Spec.MonPVCTemplate.Spec.StorageClassName).To(Equal("test-storage-class"))
+	})
+	It("should not create a device set while the provider is unknown", func() {
+		r := newReconcilerOnProvider(cloudProviderUnknown)
+		sc := templates.StorageClusterTemplate.DeepCopy()
+		Expect(r.setDeviceSetStorageParams(sc, &sc.Spec.StorageDeviceSets[0], nil)).ShouldNot(Succeed())
+	})
+})
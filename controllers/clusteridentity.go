@@ -0,0 +1,134 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	opv1a1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	clusterVersionName = "version"
+	infrastructureName = "cluster"
+	regionNodeLabelKey = "topology.kubernetes.io/region"
+
+	// partialClusterIdentityTTL is how long an identity with missing values is reused
+	// before the missing values are looked up again
+	partialClusterIdentityTTL = 10 * time.Minute
+)
+
+// Labels added to all the alerts to identify the cluster that fired them
+const (
+	clusterIDLabelKey       = "cluster_id"
+	clusterNameLabelKey     = "cluster_name"
+	regionLabelKey          = "region"
+	addonNameLabelKey       = "addon_name"
+	deployerVersionLabelKey = "deployer_version"
+)
+
+var (
+	clusterVersionGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "ClusterVersion"}
+	infrastructureGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Infrastructure"}
+)
+
+// clusterIdentity holds the values that identify the cluster in alerts
+type clusterIdentity struct {
+	clusterID       string
+	clusterName     string
+	region          string
+	addonName       string
+	deployerVersion string
+}
+
+// labels returns the alert labels of the known identity values
+func (ci *clusterIdentity) labels() map[string]string {
+	labels := map[string]string{}
+	for key, value := range map[string]string{
+		clusterIDLabelKey:       ci.clusterID,
+		clusterNameLabelKey:     ci.clusterName,
+		regionLabelKey:          ci.region,
+		addonNameLabelKey:       ci.addonName,
+		deployerVersionLabelKey: ci.deployerVersion,
+	} {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// getClusterIdentity returns the identity of the cluster. The cluster ID and name are read
+// from the ClusterVersion and Infrastructure objects, falling back to the values provided by
+// the environment when these objects are not reachable. The identity is cached on the
+// reconciler, for partialClusterIdentityTTL while some values are still unknown.
+func (r *ManagedOCSReconciler) getClusterIdentity() *clusterIdentity {
+	now := time.Now()
+	if r.clusterIdentity != nil && (r.clusterIdentityExpiry.IsZero() || now.Before(r.clusterIdentityExpiry)) {
+		return r.clusterIdentity
+	}
+
+	identity := &clusterIdentity{
+		clusterID:   r.ClusterID,
+		clusterName: r.ClusterName,
+		addonName:   r.AddonName,
+	}
+
+	clusterVersion := &unstructured.Unstructured{}
+	clusterVersion.SetGroupVersionKind(clusterVersionGVK)
+	clusterVersion.SetName(clusterVersionName)
+	if err := r.UnrestrictedClient.Get(r.ctx, client.ObjectKeyFromObject(clusterVersion), clusterVersion); err != nil {
+		r.Log.V(-1).Info("Unable to get the ClusterVersion, using the cluster ID from the environment", "error", err.Error())
+	} else if clusterID, _, _ := unstructured.NestedString(clusterVersion.Object, "spec", "clusterID"); clusterID != "" {
+		identity.clusterID = clusterID
+	}
+
+	infrastructure := &unstructured.Unstructured{}
+	infrastructure.SetGroupVersionKind(infrastructureGVK)
+	infrastructure.SetName(infrastructureName)
+	if err := r.UnrestrictedClient.Get(r.ctx, client.ObjectKeyFromObject(infrastructure), infrastructure); err != nil {
+		r.Log.V(-1).Info("Unable to get the Infrastructure, using the cluster name from the environment", "error", err.Error())
+	} else if clusterName, _, _ := unstructured.NestedString(infrastructure.Object, "status", "infrastructureName"); clusterName != "" {
+		identity.clusterName = clusterName
+	}
+
+	nodeList := corev1.NodeList{}
+	if err := r.UnrestrictedClient.List(r.ctx, &nodeList, client.HasLabels{workerNodeLabelKey, regionNodeLabelKey}); err != nil {
+		r.Log.V(-1).Info("Unable to list nodes to detect the cloud region", "error", err.Error())
+	} else if len(nodeList.Items) > 0 {
+		identity.region = nodeList.Items[0].Labels[regionNodeLabelKey]
+	}
+
+	csvList := opv1a1.ClusterServiceVersionList{}
+	if err := r.list(&csvList); err != nil {
+		r.Log.V(-1).Info("Unable to list csv resources to detect the deployer version", "error", err.Error())
+	} else if csv := getCSVByPrefix(csvList, deployerCSVPrefix); csv != nil {
+		identity.deployerVersion = csv.Spec.Version.String()
+	}
+
+	r.clusterIdentity = identity
+	if identity.clusterID != "" && identity.clusterName != "" && identity.region != "" && identity.deployerVersion != "" {
+		r.clusterIdentityExpiry = time.Time{}
+	} else {
+		r.clusterIdentityExpiry = now.Add(partialClusterIdentityTTL)
+	}
+	return identity
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+)
+
+var _ = Describe("Cluster identity cache", func() {
+	var r *ManagedOCSReconciler
+
+	BeforeEach(func() {
+		r = newTestReconciler(utils.NewDefaultAddonParams())
+		r.ClusterID = "test-cluster-id"
+	})
+
+	It("should reuse a partial identity until it expires", func() {
+		cached := &clusterIdentity{clusterID: "cached-cluster-id"}
+		r.clusterIdentity = cached
+		r.clusterIdentityExpiry = time.Now().Add(time.Minute)
+		Expect(r.getClusterIdentity()).To(BeIdenticalTo(cached))
+	})
+	It("should always reuse a complete identity", func() {
+		cached := &clusterIdentity{
+			clusterID:       "cached-cluster-id",
+			clusterName:     "cached-cluster-name",
+			region:          "us-east-1",
+			deployerVersion: "1.0.0",
+		}
+		r.clusterIdentity = cached
+		Expect(r.getClusterIdentity()).To(BeIdenticalTo(cached))
+	})
+	It("should look up an expired partial identity again", func() {
+		r.clusterIdentity = &clusterIdentity{clusterID: "cached-cluster-id"}
+		r.clusterIdentityExpiry = time.Now().Add(-time.Minute)
+
+		identity := r.getClusterIdentity()
+		Expect(identity.clusterID).To(Equal("test-cluster-id"))
+		Expect(r.clusterIdentity).To(BeIdenticalTo(identity))
+		// The envtest cluster has no ClusterVersion nor CSV, the identity stays partial
+		Expect(r.clusterIdentityExpiry).To(BeTemporally("~", time.Now().Add(partialClusterIdentityTTL), time.Minute))
+	})
+})
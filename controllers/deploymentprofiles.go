@@ -0,0 +1,141 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	ocsv1 "github.com/red-hat-storage/ocs-operator/api/v1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// deploymentProfile captures everything that differs between the deployment types
+// supported by the deployer. Profiles are selected by the DEPLOYMENT_TYPE env var.
+type deploymentProfile struct {
+	// getDesiredStorageCluster returns the storage cluster the deployer should enforce
+	getDesiredStorageCluster func(r *ManagedOCSReconciler) (*ocsv1.StorageCluster, error)
+
+	// alertRoutingPolicy is the built-in alert routing of the deployment, the routing
+	// policy ConfigMap is merged over it
+	alertRoutingPolicy *templates.AlertRoutingPolicy
+
+	// Network policies of the deployment, a nil template means that the
+	// policy is not needed and should not exist
+	ingressNetworkPolicyTemplate     *netv1.NetworkPolicy
+	cephIngressNetworkPolicyTemplate *netv1.NetworkPolicy
+	providerNetworkPolicyTemplate    *netv1.NetworkPolicy
+}
+
+// consumerCapacityUnit is the unit of the storage cluster size of consumer deployments
+const consumerCapacityUnit = "Ti"
+
+var deploymentProfiles = map[string]*deploymentProfile{
+	// A converged deployment runs a storage cluster that is consumed locally
+	"converged": {
+		getDesiredStorageCluster:         (*ManagedOCSReconciler).getDesiredConvergedStorageCluster,
+		alertRoutingPolicy:               &templates.DefaultAlertRoutingPolicy,
+		ingressNetworkPolicyTemplate:     &templates.NetworkPolicyTemplate,
+		cephIngressNetworkPolicyTemplate: &templates.CephNetworkPolicyTemplate,
+	},
+	// A provider deployment runs a storage cluster that is exposed to other clusters
+	"provider": {
+		getDesiredStorageCluster:         (*ManagedOCSReconciler).getDesiredProviderStorageCluster,
+		alertRoutingPolicy:               &templates.DefaultAlertRoutingPolicy,
+		ingressNetworkPolicyTemplate:     &templates.NetworkPolicyTemplate,
+		cephIngressNetworkPolicyTemplate: &templates.CephNetworkPolicyTemplate,
+		providerNetworkPolicyTemplate:    &templates.ProviderNetworkPolicyTemplate,
+	},
+	// A consumer deployment runs an external mode storage cluster that is
+	// backed by a provider cluster
+	"consumer": {
+		getDesiredStorageCluster:     (*ManagedOCSReconciler).getDesiredConsumerStorageCluster,
+		alertRoutingPolicy:           &templates.ConsumerAlertRoutingPolicy,
+		ingressNetworkPolicyTemplate: &templates.NetworkPolicyTemplate,
+	},
+}
+
+// getDesiredProviderNetworkPolicy returns the network policy of the storage provider API
+// server, which only accepts connections from the consumer CIDRs. Without any consumer CIDR
+// the API server cannot be reached.
+func (r *ManagedOCSReconciler) getDesiredProviderNetworkPolicy() (*netv1.NetworkPolicy, error) {
+	template := r.deploymentProfile.providerNetworkPolicyTemplate
+	if template == nil {
+		return nil, nil
+	}
+	if err := utils.FilterAddonParamErrors(r.addonParamsErrs, utils.ConsumerCIDRsKey); err != nil {
+		return nil, fmt.Errorf("Invalid consumer add-on parameters: %v", err)
+	}
+
+	networkPolicy := template.DeepCopy()
+	if len(r.addonParams.ConsumerCIDRs) == 0 {
+		networkPolicy.Spec.Ingress = []netv1.NetworkPolicyIngressRule{}
+		return networkPolicy, nil
+	}
+	for i := range networkPolicy.Spec.Ingress {
+		for _, cidr := range r.addonParams.ConsumerCIDRs {
+			networkPolicy.Spec.Ingress[i].From = append(networkPolicy.Spec.Ingress[i].From, netv1.NetworkPolicyPeer{
+				IPBlock: &netv1.IPBlock{CIDR: cidr},
+			})
+		}
+	}
+	return networkPolicy, nil
+}
+
+func (r *ManagedOCSReconciler) getDesiredProviderStorageCluster() (*ocsv1.StorageCluster, error) {
+	sc, err := r.getDesiredConvergedStorageCluster()
+	if err != nil {
+		return nil, err
+	}
+
+	// Consumers connect to the Ceph daemons from outside of the cluster network
+	sc.Spec.AllowRemoteStorageConsumers = true
+	sc.Spec.HostNetwork = true
+
+	return sc, nil
+}
+
+func (r *ManagedOCSReconciler) getDesiredConsumerStorageCluster() (*ocsv1.StorageCluster, error) {
+	if err := utils.FilterAddonParamErrors(r.addonParamsErrs, utils.StorageClusterSizeKey); err != nil {
+		return nil, fmt.Errorf("Invalid storage cluster add-on parameters: %v", err)
+	}
+	size := r.addonParams.StorageClusterSize
+	providerEndpoint := r.addonParams.StorageProviderEndpoint
+	onboardingTicket := r.addonParams.OnboardingTicket
+	r.Log.Info("Requested add-on settings", utils.StorageClusterSizeKey, size, utils.StorageProviderEndpointKey, providerEndpoint)
+
+	if providerEndpoint == "" {
+		return nil, fmt.Errorf("Add-on parameters secret does not contain a %s entry", utils.StorageProviderEndpointKey)
+	}
+	if onboardingTicket == "" {
+		return nil, fmt.Errorf("Add-on parameters secret does not contain a %s entry", utils.OnboardingTicketKey)
+	}
+	// A consumer does not run OSDs, the size is the capacity requested from the provider in TiB
+	if size <= 0 {
+		return nil, fmt.Errorf("Invalid storage cluster size value: %v, the requested capacity in TiB must be positive", size)
+	}
+	requestedCapacity := resource.MustParse(fmt.Sprintf("%d%s", size, consumerCapacityUnit))
+
+	sc := templates.ConsumerStorageClusterTemplate.DeepCopy()
+	sc.Spec.ExternalStorage.StorageProviderEndpoint = providerEndpoint
+	sc.Spec.ExternalStorage.OnboardingTicket = onboardingTicket
+	sc.Spec.ExternalStorage.RequestedCapacity = &requestedCapacity
+
+	return sc, nil
+}
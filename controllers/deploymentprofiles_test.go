@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	netv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var _ = Describe("Deployment profiles", func() {
+	newAddonParams := func(size int) *utils.AddonParams {
+		params := utils.NewDefaultAddonParams()
+		params.StorageClusterSize = size
+		return params
+	}
+
+	Context("provider profile", func() {
+		It("should expose the converged storage cluster to remote consumers", func() {
+			r := newTestReconciler(newAddonParams(2))
+			r.deploymentProfile = deploymentProfiles["provider"]
+
+			sc, err := r.deploymentProfile.getDesiredStorageCluster(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sc.Spec.AllowRemoteStorageConsumers).To(BeTrue())
+			Expect(sc.Spec.HostNetwork).To(BeTrue())
+			Expect(sc.Spec.StorageDeviceSets).To(HaveLen(1))
+			Expect(sc.Spec.StorageDeviceSets[0].Count).To(Equal(2))
+		})
+		It("should only allow the consumer CIDRs to reach the provider API server", func() {
+			params := newAddonParams(1)
+			params.ConsumerCIDRs = []string{"10.0.0.0/16", "10.1.0.0/16"}
+			r := newTestReconciler(params)
+			r.deploymentProfile = deploymentProfiles["provider"]
+
+			networkPolicy, err := r.getDesiredProviderNetworkPolicy()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkPolicy.Spec.Ingress).To(HaveLen(1))
+			Expect(networkPolicy.Spec.Ingress[0].Ports).To(HaveLen(1))
+			Expect(networkPolicy.Spec.Ingress[0].From).To(Equal([]netv1.NetworkPolicyPeer{
+				{IPBlock: &netv1.IPBlock{CIDR: "10.0.0.0/16"}},
+				{IPBlock: &netv1.IPBlock{CIDR: "10.1.0.0/16"}},
+			}))
+		})
+		It("should deny all ingress to the provider API server without consumer CIDRs", func() {
+			r := newTestReconciler(newAddonParams(1))
+			r.deploymentProfile = deploymentProfiles["provider"]
+
+			networkPolicy, err := r.getDesiredProviderNetworkPolicy()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkPolicy.Spec.Ingress).To(BeEmpty())
+			Expect(networkPolicy.Spec.PolicyTypes).To(ContainElement(netv1.PolicyTypeIngress))
+		})
+		It("should reject invalid consumer CIDRs", func() {
+			params, errs := utils.ParseAddonParams(map[string][]byte{
+				utils.StorageClusterSizeKey: []byte("1"),
+				utils.ConsumerCIDRsKey:      []byte("10.0.0.0/16, not-a-cidr"),
+			})
+			r := newTestReconciler(params)
+			r.addonParamsErrs = errs
+			r.deploymentProfile = deploymentProfiles["provider"]
+
+			_, err := r.getDesiredProviderNetworkPolicy()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("consumer profile", func() {
+		newConsumerAddonParams := func(size int) *utils.AddonParams {
+			params := newAddonParams(size)
+			params.StorageProviderEndpoint = "provider.example.com:50051"
+			params.OnboardingTicket = "test-ticket"
+			return params
+		}
+
+		It("should request the storage cluster size in TiB from the provider", func() {
+			r := newTestReconciler(newConsumerAddonParams(4))
+			r.deploymentProfile = deploymentProfiles["consumer"]
+
+			sc, err := r.deploymentProfile.getDesiredStorageCluster(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sc.Spec.ExternalStorage.StorageProviderEndpoint).To(Equal("provider.example.com:50051"))
+			Expect(sc.Spec.ExternalStorage.OnboardingTicket).To(Equal("test-ticket"))
+			Expect(sc.Spec.ExternalStorage.RequestedCapacity).ToNot(BeNil())
+			Expect(sc.Spec.ExternalStorage.RequestedCapacity.Cmp(resource.MustParse("4Ti"))).To(Equal(0))
+		})
+		It("should reject a storage cluster size that is not positive", func() {
+			r := newTestReconciler(newConsumerAddonParams(0))
+			r.deploymentProfile = deploymentProfiles["consumer"]
+
+			_, err := r.deploymentProfile.getDesiredStorageCluster(r)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should require the provider endpoint and onboarding ticket", func() {
+			params := newConsumerAddonParams(1)
+			params.StorageProviderEndpoint = ""
+			r := newTestReconciler(params)
+			r.deploymentProfile = deploymentProfiles["consumer"]
+			_, err := r.deploymentProfile.getDesiredStorageCluster(r)
+			Expect(err).To(HaveOccurred())
+
+			params = newConsumerAddonParams(1)
+			params.OnboardingTicket = ""
+			r = newTestReconciler(params)
+			r.deploymentProfile = deploymentProfiles["consumer"]
+			_, err = r.deploymentProfile.getDesiredStorageCluster(r)
+			Expect(err).To(HaveOccurred())
+		})
+		It("should not run any provider network policy", func() {
+			r := newTestReconciler(newConsumerAddonParams(1))
+			r.deploymentProfile = deploymentProfiles["consumer"]
+
+			networkPolicy, err := r.getDesiredProviderNetworkPolicy()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkPolicy).To(BeNil())
+		})
+	})
+})
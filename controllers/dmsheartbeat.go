@@ -0,0 +1,200 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+)
+
+const (
+	alertmanagerServiceURLFormat = "http://alertmanager-operated.%s.svc:9093"
+	alertmanagerQueryTimeout     = 10 * time.Second
+
+	defaultDMSHeartbeatInterval = 5 * time.Minute
+
+	// The heartbeat is reported stale when no notification was delivered for
+	// this many heartbeat intervals
+	dmsHeartbeatStaleFactor = 3
+
+	notificationsTotalMetric  = "alertmanager_notifications_total"
+	notificationsFailedMetric = "alertmanager_notifications_failed_total"
+	receiverLabelKey          = "receiver"
+)
+
+// alertmanagerMetricsProvider exposes the notification delivery counters of the
+// managed Alertmanager instance
+type alertmanagerMetricsProvider interface {
+	// getDMSDeliveries returns the number of notifications that were delivered
+	// successfully to the DMS receiver since alertmanager started
+	getDMSDeliveries(ctx context.Context) (float64, error)
+}
+
+// httpAlertmanagerMetrics reads the metrics endpoint of the managed Alertmanager instance
+type httpAlertmanagerMetrics struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+func newHTTPAlertmanagerMetrics(namespace string) *httpAlertmanagerMetrics {
+	return &httpAlertmanagerMetrics{
+		endpoint:   fmt.Sprintf(alertmanagerServiceURLFormat, namespace),
+		httpClient: &http.Client{Timeout: alertmanagerQueryTimeout},
+	}
+}
+
+// getDMSDeliveries sums the notification counters of the DMS receiver. Alertmanager labels
+// its counters with the receiver since version 0.26. The receivers of an AlertmanagerConfig
+// are named <namespace>/<config name>/<receiver name> by the prometheus operator.
+func (a *httpAlertmanagerMetrics) getDMSDeliveries(ctx context.Context) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint+"/metrics", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to query alertmanager: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("alertmanager metrics request failed with status %s", resp.Status)
+	}
+
+	total, failed := 0.0, 0.0
+	found := false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		name, labels, value, ok := parseMetricLine(scanner.Text())
+		if !ok || !isAlertmanagerConfigReceiver(labels[receiverLabelKey], templates.DeadMansSnitchReceiverName) {
+			continue
+		}
+		switch name {
+		case notificationsTotalMetric:
+			total += value
+			found = true
+		case notificationsFailedMetric:
+			failed += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("unable to read alertmanager metrics: %v", err)
+	}
+	if !found {
+		return 0, fmt.Errorf("alertmanager does not report the notifications of the %s receiver", templates.DeadMansSnitchReceiverName)
+	}
+	return total - failed, nil
+}
+
+// isAlertmanagerConfigReceiver checks whether an alertmanager receiver is the receiver with the
+// given name. The receivers of an AlertmanagerConfig are prefixed by the prometheus operator.
+func isAlertmanagerConfigReceiver(receiver string, name string) bool {
+	return receiver == name || strings.HasSuffix(receiver, "/"+name)
+}
+
+// parseMetricLine parses a sample of the Prometheus text exposition format. Comments and
+// malformed lines are reported as not ok.
+func parseMetricLine(line string) (string, map[string]string, float64, bool) {
+	if strings.HasPrefix(line, "#") {
+		return "", nil, 0, false
+	}
+	labels := map[string]string{}
+	name, rest := line, ""
+	if open := strings.Index(line, "{"); open >= 0 {
+		closing := strings.LastIndex(line, "}")
+		if closing < open {
+			return "", nil, 0, false
+		}
+		name, rest = line[:open], line[closing+1:]
+		for _, pair := range strings.Split(line[open+1:closing], ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			if value, err := strconv.Unquote(strings.TrimSpace(kv[1])); err == nil {
+				labels[strings.TrimSpace(kv[0])] = value
+			}
+		}
+	} else if space := strings.Index(line, " "); space >= 0 {
+		name, rest = line[:space], line[space:]
+	}
+	fields := strings.Fields(rest)
+	if name == "" || len(fields) == 0 {
+		return "", nil, 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, false
+	}
+	return name, labels, value, true
+}
+
+// getDMSHeartbeatPolicyOverrides returns the routing policy overrides that send the DMS
+// heartbeat at the configured interval
+func (r *ManagedOCSReconciler) getDMSHeartbeatPolicyOverrides() *templates.AlertRoutingPolicy {
+	interval := fmt.Sprintf("%ds", int(r.DMSHeartbeatInterval.Seconds()))
+	return &templates.AlertRoutingPolicy{
+		Routes: []templates.AlertRoutingRule{{
+			Receiver:       templates.DeadMansSnitchReceiverName,
+			GroupInterval:  interval,
+			RepeatInterval: interval,
+		}},
+	}
+}
+
+// reconcileDMSHeartbeat checks that alertmanager keeps delivering the DMS heartbeat. A
+// delivery is observed when the count of successful notifications of the DMS receiver grows.
+// Failures to read the count are tolerated until the heartbeat is stale.
+func (r *ManagedOCSReconciler) reconcileDMSHeartbeat() error {
+	if r.alertmanagerMetrics == nil {
+		r.alertmanagerMetrics = newHTTPAlertmanagerMetrics(r.namespace)
+	}
+
+	now := time.Now()
+	if r.dmsLastDeliveryTime.IsZero() {
+		// Give alertmanager a full stale period after the deployer starts
+		r.dmsLastDeliveryTime = now
+	}
+	r.requestRequeueAfter(r.DMSHeartbeatInterval)
+
+	deliveries, err := r.alertmanagerMetrics.getDMSDeliveries(r.ctx)
+	if err != nil {
+		r.Log.V(-1).Info("Unable to check the DMS heartbeat", "error", err.Error())
+	} else {
+		// A lower count means alertmanager restarted, which resets its counters. The
+		// count then holds the deliveries since the restart.
+		if deliveries > r.dmsDeliveries || (deliveries < r.dmsDeliveries && deliveries > 0) {
+			r.dmsLastDeliveryTime = now
+		}
+		r.dmsDeliveries = deliveries
+	}
+
+	staleAfter := dmsHeartbeatStaleFactor * r.DMSHeartbeatInterval
+	if since := now.Sub(r.dmsLastDeliveryTime); since > staleAfter {
+		if err != nil {
+			return fmt.Errorf("DMS heartbeat was not observed for %v: %v", since.Round(time.Second), err)
+		}
+		return fmt.Errorf("DMS heartbeat was not delivered for %v", since.Round(time.Second))
+	}
+	return nil
+}
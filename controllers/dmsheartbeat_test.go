@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+)
+
+type fakeAlertmanagerMetrics struct {
+	deliveries float64
+	err        error
+}
+
+func (m *fakeAlertmanagerMetrics) getDMSDeliveries(ctx context.Context) (float64, error) {
+	return m.deliveries, m.err
+}
+
+var _ = Describe("DMS heartbeat", func() {
+	const interval = time.Minute
+
+	var (
+		r       *ManagedOCSReconciler
+		metrics *fakeAlertmanagerMetrics
+	)
+
+	// setLastDelivery moves the last observed delivery back in time
+	setLastDelivery := func(ago time.Duration) {
+		r.dmsLastDeliveryTime = time.Now().Add(-ago)
+	}
+
+	BeforeEach(func() {
+		metrics = &fakeAlertmanagerMetrics{deliveries: 10}
+		r = newTestReconciler(utils.NewDefaultAddonParams())
+		r.DMSHeartbeatInterval = interval
+		r.alertmanagerMetrics = metrics
+		Expect(r.reconcileDMSHeartbeat()).Should(Succeed())
+		Expect(r.dmsDeliveries).To(Equal(10.0))
+	})
+
+	It("should report a stale heartbeat when the DMS receiver deliveries do not grow", func() {
+		setLastDelivery(2 * interval)
+		Expect(r.reconcileDMSHeartbeat()).Should(Succeed())
+
+		setLastDelivery(dmsHeartbeatStaleFactor*interval + time.Second)
+		Expect(r.reconcileDMSHeartbeat()).ShouldNot(Succeed())
+
+		metrics.deliveries++
+		Expect(r.reconcileDMSHeartbeat()).Should(Succeed())
+		Expect(r.dmsLastDeliveryTime).To(BeTemporally("~", time.Now(), time.Second))
+	})
+	It("should report the reason of a stale heartbeat that cannot be checked", func() {
+		metrics.err = fmt.Errorf("alertmanager is unreachable")
+		Expect(r.reconcileDMSHeartbeat()).Should(Succeed())
+
+		setLastDelivery(dmsHeartbeatStaleFactor*interval + time.Second)
+		err := r.reconcileDMSHeartbeat()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("alertmanager is unreachable"))
+	})
+	It("should count the deliveries since an alertmanager restart as a delivery", func() {
+		setLastDelivery(dmsHeartbeatStaleFactor*interval + time.Second)
+		metrics.deliveries = 2
+		Expect(r.reconcileDMSHeartbeat()).Should(Succeed())
+		Expect(r.dmsDeliveries).To(Equal(2.0))
+	})
+	It("should not count an alertmanager restart without deliveries as a delivery", func() {
+		setLastDelivery(dmsHeartbeatStaleFactor*interval + time.Second)
+		metrics.deliveries = 0
+		Expect(r.reconcileDMSHeartbeat()).ShouldNot(Succeed())
+		Expect(r.dmsDeliveries).To(BeZero())
+
+		metrics.deliveries = 1
+		Expect(r.reconcileDMSHeartbeat()).Should(Succeed())
+	})
+
+	It("should only count the notifications of the DMS receiver", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, `# HELP alertmanager_notifications_total The total number of attempted notifications.
+# TYPE alertmanager_notifications_total counter
+alertmanager_notifications_total{integration="webhook",receiver="openshift-storage/managed-ocs-alertmanager-config/DeadMansSnitch"} 7
+alertmanager_notifications_total{integration="webhook",receiver="openshift-storage/managed-ocs-alertmanager-config/webhook"} 40
+alertmanager_notifications_total{integration="email",receiver="openshift-storage/managed-ocs-alertmanager-config/SendGrid"} 3
+alertmanager_notifications_failed_total{integration="webhook",receiver="openshift-storage/managed-ocs-alertmanager-config/DeadMansSnitch"} 2
+alertmanager_notifications_failed_total{integration="webhook",receiver="openshift-storage/managed-ocs-alertmanager-config/webhook"} 1
+`)
+		}))
+		defer server.Close()
+
+		provider := &httpAlertmanagerMetrics{endpoint: server.URL, httpClient: server.Client()}
+		Expect(provider.getDMSDeliveries(context.Background())).To(Equal(5.0))
+	})
+	It("should fail when alertmanager does not report the notifications per receiver", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, `alertmanager_notifications_total{integration="webhook"} 47
+`)
+		}))
+		defer server.Close()
+
+		provider := &httpAlertmanagerMetrics{endpoint: server.URL, httpClient: server.Client()}
+		_, err := provider.getDMSDeliveries(context.Background())
+		Expect(err).To(HaveOccurred())
+	})
+})
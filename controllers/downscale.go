@@ -0,0 +1,419 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	defaultDownscaleMaxUsageRatio = 0.75
+
+	// downscaleStepInterval is the minimal time between two device set count
+	// reductions, giving Ceph the time to start rebalancing before the placement
+	// groups state is checked again
+	downscaleStepInterval    = 5 * time.Minute
+	downscaleRequeueInterval = 1 * time.Minute
+
+	// osdOutSettleInterval is the minimal time between marking an OSD out and checking that
+	// its data was moved, giving Ceph the time to remap the placement groups of the OSD
+	osdOutSettleInterval = 1 * time.Minute
+
+	// Rook does not remove OSDs when the device set count is reduced. The OSDs of a removed
+	// device set are marked out and then purged by jobs, one OSD at a time, the same way
+	// ocs-operator removes failed OSDs.
+	osdOutJobName                  = "managed-ocs-osd-out"
+	osdRemovalJobName              = "managed-ocs-osd-removal"
+	osdRemovalJobOSDIDsAnnotation  = "ocs.openshift.io/osd-ids"
+	rookCephOperatorDeploymentName = "rook-ceph-operator"
+
+	// Labels of the OSD deployments created by Rook. Each replica of an OCS device set is a
+	// Rook device set, named after the OCS device set and the replica index, and holds one
+	// OSD per device set count step, identified by its set index.
+	osdAppLabelValue     = "rook-ceph-osd"
+	osdIDLabelKey        = "ceph-osd-id"
+	osdDeviceSetLabelKey = "ceph.rook.io/DeviceSet"
+	osdSetIndexLabelKey  = "ceph.rook.io/setIndex"
+	osdAppLabelKey       = "app"
+)
+
+// getDownscaleStepCount returns the device set count that should be applied for a request
+// to reduce the count from currCount to desiredCount. A step removes a single device set,
+// which holds one OSD for each of its replicas, and only when the remaining capacity can hold
+// the used data, all the OSDs are up and all placement groups are active+clean. The OSDs of the
+// step are then removed one at a time, and the device set count is held until Ceph no longer
+// reports any of them. The progress is recorded in the ManagedOCS status.
+func (r *ManagedOCSReconciler) getDownscaleStepCount(currCount int, desiredCount int, replica int) int {
+	status := &v1.DownscaleStatus{}
+	if r.managedOCS.Status.Downscale != nil {
+		status = r.managedOCS.Status.Downscale.DeepCopy()
+	}
+	status.RequestedCount = desiredCount
+	status.CurrentCount = currCount
+	r.managedOCS.Status.Downscale = status
+
+	// The request is re-evaluated periodically until it is fulfilled or withdrawn
+	r.requestRequeueAfter(downscaleRequeueInterval)
+
+	if r.cephMetrics == nil {
+		r.cephMetrics = newPrometheusCephMetrics(r.namespace)
+	}
+
+	// A step is always completed, even when the request is withdrawn in the meantime
+	if len(status.RemovingOSDIDs) > 0 {
+		removed, err := r.reconcileOSDRemoval(status)
+		if err != nil {
+			r.Log.Error(err, "Unable to remove the OSD of the storage device set", "osd", status.RemovingOSDIDs[0])
+			status.State = v1.DownscaleInProgress
+			status.Message = fmt.Sprintf("Unable to remove OSD %d: %v", status.RemovingOSDIDs[0], err)
+			return currCount
+		}
+		if !removed {
+			return currCount
+		}
+		r.Log.Info("Removed the OSDs of the storage device set", "osds", status.RemovingOSDIDs)
+		status.RemovingOSDIDs = nil
+		if currCount <= desiredCount {
+			r.updateDownscaleStatus(currCount, desiredCount)
+			return desiredCount
+		}
+	}
+
+	used, total, err := r.cephMetrics.getCapacity(r.ctx)
+	if err != nil {
+		return r.refuseDownscale(status, fmt.Sprintf("Unable to verify the Ceph capacity: %v", err))
+	}
+	// All device set count steps provide the same raw capacity
+	projectedUsageRatio := used / (total * float64(desiredCount) / float64(currCount))
+	if projectedUsageRatio > r.DownscaleMaxUsageRatio {
+		return r.refuseDownscale(status, fmt.Sprintf(
+			"Ceph usage would reach %.1f%% after the downscale, which exceeds the allowed %.1f%%",
+			projectedUsageRatio*100,
+			r.DownscaleMaxUsageRatio*100,
+		))
+	}
+
+	if status.LastStepTime != nil && time.Since(status.LastStepTime.Time) < downscaleStepInterval {
+		status.State = v1.DownscaleInProgress
+		status.Message = "Waiting for Ceph to start rebalancing data after the last OSD removal"
+		return currCount
+	}
+	osdUpStates, err := r.cephMetrics.getOSDUpStates(r.ctx)
+	if err != nil {
+		return r.refuseDownscale(status, fmt.Sprintf("Unable to verify the OSDs state: %v", err))
+	}
+	for _, id := range sortedOSDIDs(osdUpStates) {
+		if !osdUpStates[id] {
+			status.State = v1.DownscaleInProgress
+			status.Message = fmt.Sprintf("Waiting for OSD %d to be up before removing more OSDs", id)
+			return currCount
+		}
+	}
+	activeClean, err := r.cephMetrics.arePGsActiveClean(r.ctx)
+	if err != nil {
+		return r.refuseDownscale(status, fmt.Sprintf("Unable to verify the placement groups state: %v", err))
+	}
+	if !activeClean {
+		status.State = v1.DownscaleInProgress
+		status.Message = "Waiting for all placement groups to be active+clean before removing more OSDs"
+		return currCount
+	}
+
+	stepCount := currCount - 1
+	osdIDs, err := r.getDeviceSetStepOSDIDs(stepCount, replica)
+	if err != nil {
+		return r.refuseDownscale(status, fmt.Sprintf("Unable to find the OSDs of the storage device set: %v", err))
+	}
+	if len(osdIDs) != replica {
+		return r.refuseDownscale(status, fmt.Sprintf("Found %d OSDs for storage device set count step %d, expected %d", len(osdIDs), stepCount, replica))
+	}
+	r.Log.Info("Reducing storage device set count", "Current", currCount, "New", stepCount, "Requested", desiredCount, "osds", osdIDs)
+	now := metav1.Now()
+	status.State = v1.DownscaleInProgress
+	status.Message = fmt.Sprintf("Reducing the storage device set count to %d, removing OSDs %s one at a time", stepCount, formatOSDIDs(osdIDs))
+	status.LastStepTime = &now
+	status.RemovingOSDIDs = osdIDs
+	r.recordEvent(eventReasonDownscaleStep, "Reducing the storage device set count from %d to %d, requested %d, removing OSDs %s",
+		currCount, stepCount, desiredCount, formatOSDIDs(osdIDs))
+	return stepCount
+}
+
+// isOSDRemovalInProgress returns true while the OSDs of a removed device set are being purged
+func (r *ManagedOCSReconciler) isOSDRemovalInProgress() bool {
+	status := r.managedOCS.Status.Downscale
+	return status != nil && len(status.RemovingOSDIDs) > 0
+}
+
+// getDeviceSetStepOSDIDs returns the IDs of the OSDs that back the device set count step
+// with the given index, one for each replica of the device set
+func (r *ManagedOCSReconciler) getDeviceSetStepOSDIDs(index int, replica int) ([]int, error) {
+	deviceSets := map[string]bool{}
+	for i := 0; i < replica; i++ {
+		deviceSets[fmt.Sprintf("%s-%d", deviceSetName, i)] = true
+	}
+
+	deploymentList := appsv1.DeploymentList{}
+	listOptions := []client.ListOption{
+		client.InNamespace(r.namespace),
+		client.MatchingLabels{
+			osdAppLabelKey:      osdAppLabelValue,
+			osdSetIndexLabelKey: strconv.Itoa(index),
+		},
+	}
+	if err := r.Client.List(r.ctx, &deploymentList, listOptions...); err != nil {
+		return nil, fmt.Errorf("unable to list OSD deployments: %v", err)
+	}
+
+	osdIDs := []int{}
+	for i := range deploymentList.Items {
+		labels := deploymentList.Items[i].Labels
+		if !deviceSets[labels[osdDeviceSetLabelKey]] {
+			continue
+		}
+		id, err := strconv.Atoi(labels[osdIDLabelKey])
+		if err != nil {
+			return nil, fmt.Errorf("OSD deployment %s has an invalid OSD ID", deploymentList.Items[i].Name)
+		}
+		osdIDs = append(osdIDs, id)
+	}
+	sort.Ints(osdIDs)
+	return osdIDs, nil
+}
+
+// reconcileOSDRemoval removes the OSDs of the current downscale step one at a time. Each OSD is
+// marked out while it is still up, and only once Ceph moved its data to the other OSDs and all
+// placement groups are active+clean, it is stopped and purged. It returns true once Ceph no
+// longer reports any of the OSDs of the step.
+func (r *ManagedOCSReconciler) reconcileOSDRemoval(status *v1.DownscaleStatus) (bool, error) {
+	id := status.RemovingOSDIDs[0]
+	status.State = v1.DownscaleInProgress
+
+	if status.OSDOutTime == nil {
+		// The placement groups must be clean before taking out each OSD, including after
+		// the purge of the previous one
+		activeClean, err := r.cephMetrics.arePGsActiveClean(r.ctx)
+		if err != nil {
+			return false, fmt.Errorf("unable to verify the placement groups state: %v", err)
+		}
+		if !activeClean {
+			status.Message = fmt.Sprintf("Waiting for all placement groups to be active+clean before marking OSD %d out", id)
+			return false, nil
+		}
+		r.Log.Info("Marking OSD out", "osd", id)
+		now := metav1.Now()
+		status.OSDOutTime = &now
+	}
+	outJob, err := r.reconcileOSDJob(status, osdOutJobName, &templates.OSDOutJobTemplate, id)
+	if outJob == nil || err != nil {
+		return false, err
+	}
+
+	// An out OSD holds no data once all placement groups are active+clean again
+	outTime := status.OSDOutTime.Time
+	if outJob.Status.CompletionTime != nil {
+		outTime = outJob.Status.CompletionTime.Time
+	}
+	if time.Since(outTime) < osdOutSettleInterval {
+		status.Message = fmt.Sprintf("Waiting for Ceph to start moving the data of OSD %d", id)
+		return false, nil
+	}
+	activeClean, err := r.cephMetrics.arePGsActiveClean(r.ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to verify the placement groups state: %v", err)
+	}
+	if !activeClean {
+		status.Message = fmt.Sprintf("Waiting for the data of OSD %d to be moved to other OSDs", id)
+		return false, nil
+	}
+
+	// Ceph only purges OSDs that are down
+	if err := r.scaleDownOSD(id); err != nil {
+		return false, err
+	}
+	if removalJob, err := r.reconcileOSDJob(status, osdRemovalJobName, &templates.OSDRemovalJobTemplate, id); removalJob == nil || err != nil {
+		return false, err
+	}
+
+	// The job succeeded, the OSD is removed once Ceph no longer reports it
+	osdUpStates, err := r.cephMetrics.getOSDUpStates(r.ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to verify the OSDs state: %v", err)
+	}
+	if _, found := osdUpStates[id]; found {
+		status.Message = fmt.Sprintf("Waiting for Ceph to report the removal of OSD %d", id)
+		return false, nil
+	}
+	r.Log.Info("Removed OSD of the storage device set", "osd", id)
+	status.RemovingOSDIDs = status.RemovingOSDIDs[1:]
+	status.OSDOutTime = nil
+	if len(status.RemovingOSDIDs) > 0 {
+		status.Message = fmt.Sprintf("Removed OSD %d, removing OSDs %s", id, formatOSDIDs(status.RemovingOSDIDs))
+		return false, nil
+	}
+	return true, nil
+}
+
+// reconcileOSDJob runs the given OSD job for a single OSD. It returns the job once it
+// succeeded, or nil while it is still running.
+func (r *ManagedOCSReconciler) reconcileOSDJob(status *v1.DownscaleStatus, name string, template *batchv1.Job, osdID int) (*batchv1.Job, error) {
+	osdIDs := strconv.Itoa(osdID)
+
+	job := &batchv1.Job{}
+	job.Name = name
+	job.Namespace = r.namespace
+	if err := r.get(job); err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		r.Log.Info("Creating OSD job", "name", name, "osd", osdID)
+		status.Message = fmt.Sprintf("Waiting for the %s job of OSD %d to complete", name, osdID)
+		return nil, r.createOSDJob(job, template, osdIDs)
+	}
+
+	// A job of a previous OSD, or a failed job, is removed and created again
+	propagation := client.PropagationPolicy(metav1.DeletePropagationBackground)
+	if job.Annotations[osdRemovalJobOSDIDsAnnotation] != osdIDs {
+		r.Log.Info("Deleting the OSD job of a previous OSD", "name", name, "osds", job.Annotations[osdRemovalJobOSDIDsAnnotation])
+		return nil, client.IgnoreNotFound(r.Client.Delete(r.ctx, job, propagation))
+	}
+	if job.Status.Failed > 0 {
+		r.recordWarning(eventReasonDownscaleRefused, "The %s job of OSD %d failed, retrying", name, osdID)
+		status.Message = fmt.Sprintf("The %s job of OSD %d failed, retrying", name, osdID)
+		return nil, client.IgnoreNotFound(r.Client.Delete(r.ctx, job, propagation))
+	}
+	if job.Status.Succeeded == 0 {
+		status.Message = fmt.Sprintf("Waiting for the %s job of OSD %d to complete", name, osdID)
+		return nil, nil
+	}
+	return job, nil
+}
+
+// scaleDownOSD stops the OSD daemon with the given ID
+func (r *ManagedOCSReconciler) scaleDownOSD(osdID int) error {
+	deployment := &appsv1.Deployment{}
+	deployment.Name = fmt.Sprintf("%s-%d", osdAppLabelValue, osdID)
+	deployment.Namespace = r.namespace
+	if err := r.get(deployment); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("unable to get OSD deployment %s: %v", deployment.Name, err)
+	}
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 0 {
+		return nil
+	}
+	r.Log.Info("Scaling down OSD deployment", "name", deployment.Name)
+	replicas := int32(0)
+	deployment.Spec.Replicas = &replicas
+	if err := r.update(deployment); err != nil {
+		return fmt.Errorf("unable to scale down OSD deployment %s: %v", deployment.Name, err)
+	}
+	return nil
+}
+
+func (r *ManagedOCSReconciler) createOSDJob(job *batchv1.Job, template *batchv1.Job, osdIDs string) error {
+	// The job runs the rook image used by the rook operator
+	rookOperator := &appsv1.Deployment{}
+	rookOperator.Name = rookCephOperatorDeploymentName
+	rookOperator.Namespace = r.namespace
+	if err := r.get(rookOperator); err != nil {
+		return fmt.Errorf("unable to get the rook operator deployment: %v", err)
+	}
+	if len(rookOperator.Spec.Template.Spec.Containers) == 0 {
+		return fmt.Errorf("rook operator deployment has no containers")
+	}
+
+	desired := template.DeepCopy()
+	job.Annotations = map[string]string{osdRemovalJobOSDIDsAnnotation: osdIDs}
+	job.Spec = desired.Spec
+	container := &job.Spec.Template.Spec.Containers[0]
+	container.Image = rookOperator.Spec.Template.Spec.Containers[0].Image
+	container.Env = append(container.Env, corev1.EnvVar{Name: templates.OSDJobIDsEnvVarName, Value: osdIDs})
+	if err := r.own(job); err != nil {
+		return err
+	}
+	return r.Client.Create(r.ctx, job)
+}
+
+func (r *ManagedOCSReconciler) refuseDownscale(status *v1.DownscaleStatus, reason string) int {
+	r.Log.V(-1).Info("Requested storage device set count will result in downscaling, which is not safe. Skipping", "reason", reason)
+	if status.State != v1.DownscaleRefused {
+		r.recordWarning(eventReasonDownscaleRefused, "Refused to reduce the storage device set count to %d: %s", status.RequestedCount, reason)
+	}
+	status.State = v1.DownscaleRefused
+	status.Message = reason
+	return status.CurrentCount
+}
+
+// updateDownscaleStatus updates a previously recorded downscale status once the requested
+// count is no longer lower than the current count
+func (r *ManagedOCSReconciler) updateDownscaleStatus(currCount int, desiredCount int) {
+	status := r.managedOCS.Status.Downscale
+	if status == nil {
+		return
+	}
+	if currCount == status.RequestedCount && desiredCount == currCount {
+		if status.State != v1.DownscaleCompleted {
+			r.recordEvent(eventReasonDownscaleCompleted, "Reduced the storage device set count to %d", currCount)
+		}
+		status.State = v1.DownscaleCompleted
+		status.CurrentCount = currCount
+		status.Message = ""
+	} else {
+		// The downscale request was withdrawn
+		r.managedOCS.Status.Downscale = nil
+	}
+}
+
+// requestRequeueAfter asks for the ManagedOCS resource to be reconciled again
+// within the given duration, even when nothing changes in the cluster
+func (r *ManagedOCSReconciler) requestRequeueAfter(duration time.Duration) {
+	if r.requeueAfter == 0 || duration < r.requeueAfter {
+		r.requeueAfter = duration
+	}
+}
+
+// formatOSDIDs returns the OSD IDs as a comma separated list
+func formatOSDIDs(osdIDs []int) string {
+	ids := make([]string, len(osdIDs))
+	for i, id := range osdIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, ",")
+}
+
+func sortedOSDIDs(osdStates map[int]bool) []int {
+	ids := []int{}
+	for id := range osdStates {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type fakeCephMetrics struct {
+	used        float64
+	total       float64
+	activeClean bool
+	osdUp       map[int]bool
+	err         error
+}
+
+func (m *fakeCephMetrics) getCapacity(ctx context.Context) (float64, float64, error) {
+	return m.used, m.total, m.err
+}
+
+func (m *fakeCephMetrics) arePGsActiveClean(ctx context.Context) (bool, error) {
+	return m.activeClean, m.err
+}
+
+func (m *fakeCephMetrics) getOSDUpStates(ctx context.Context) (map[int]bool, error) {
+	return m.osdUp, m.err
+}
+
+var _ = Describe("Storage device set downscale", func() {
+	const (
+		replica      = 3
+		currCount    = 2
+		desiredCount = 1
+	)
+
+	var metrics *fakeCephMetrics
+
+	newDownscaleReconciler := func() *ManagedOCSReconciler {
+		r := newTestReconciler(utils.NewDefaultAddonParams())
+		r.DownscaleMaxUsageRatio = defaultDownscaleMaxUsageRatio
+		r.cephMetrics = metrics
+		return r
+	}
+
+	BeforeEach(func() {
+		metrics = &fakeCephMetrics{
+			used:        10,
+			total:       100,
+			activeClean: true,
+			osdUp:       map[int]bool{0: true, 1: true, 2: true, 3: true, 4: true, 5: true},
+		}
+	})
+
+	It("should refuse to downscale when the remaining capacity cannot hold the used data", func() {
+		metrics.used = 40
+		r := newDownscaleReconciler()
+		Expect(r.getDownscaleStepCount(currCount, desiredCount, replica)).To(Equal(currCount))
+		Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleRefused))
+		Expect(r.managedOCS.Status.Downscale.RemovingOSDIDs).To(BeEmpty())
+	})
+	It("should refuse to downscale when the Ceph metrics are unavailable", func() {
+		metrics.err = fmt.Errorf("prometheus is unreachable")
+		r := newDownscaleReconciler()
+		Expect(r.getDownscaleStepCount(currCount, desiredCount, replica)).To(Equal(currCount))
+		Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleRefused))
+	})
+	It("should wait while an OSD is down", func() {
+		metrics.osdUp[4] = false
+		r := newDownscaleReconciler()
+		Expect(r.getDownscaleStepCount(currCount, desiredCount, replica)).To(Equal(currCount))
+		Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleInProgress))
+		Expect(r.managedOCS.Status.Downscale.Message).To(ContainSubstring("OSD 4"))
+	})
+	It("should wait while the placement groups are not active+clean", func() {
+		metrics.activeClean = false
+		r := newDownscaleReconciler()
+		Expect(r.getDownscaleStepCount(currCount, desiredCount, replica)).To(Equal(currCount))
+		Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleInProgress))
+		Expect(r.managedOCS.Status.Downscale.RemovingOSDIDs).To(BeEmpty())
+	})
+	It("should wait for the placement groups to be active+clean before marking the next OSD out", func() {
+		metrics.activeClean = false
+		r := newDownscaleReconciler()
+		r.managedOCS.Status.Downscale = &v1.DownscaleStatus{RemovingOSDIDs: []int{4, 5}}
+		Expect(r.getDownscaleStepCount(desiredCount, desiredCount, replica)).To(Equal(desiredCount))
+		Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleInProgress))
+		Expect(r.managedOCS.Status.Downscale.Message).To(ContainSubstring("before marking OSD 4 out"))
+		Expect(r.managedOCS.Status.Downscale.OSDOutTime).To(BeNil())
+		Expect(r.isOSDRemovalInProgress()).To(BeTrue())
+	})
+
+	Context("with the OSDs of two device set count steps", func() {
+		ctx := context.Background()
+
+		newDeployment := func(name string, labels map[string]string) *appsv1.Deployment {
+			deployment := &appsv1.Deployment{}
+			deployment.Name = name
+			deployment.Namespace = testSecondaryNamespace
+			deployment.Labels = labels
+			deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}}
+			deployment.Spec.Template.Labels = map[string]string{"app": name}
+			deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main", Image: "test-image"}}
+			return deployment
+		}
+
+		// OSDs 0-2 back the first step and OSDs 3-5 the second one, one for each replica
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, newDeployment(rookCephOperatorDeploymentName, nil))).Should(Succeed())
+			for id := 0; id < currCount*replica; id++ {
+				Expect(k8sClient.Create(ctx, newDeployment(fmt.Sprintf("%s-%d", osdAppLabelValue, id), map[string]string{
+					osdAppLabelKey:       osdAppLabelValue,
+					osdIDLabelKey:        strconv.Itoa(id),
+					osdDeviceSetLabelKey: fmt.Sprintf("%s-%d", deviceSetName, id%replica),
+					osdSetIndexLabelKey:  strconv.Itoa(id / replica),
+				}))).Should(Succeed())
+			}
+		})
+		AfterEach(func() {
+			Expect(k8sClient.DeleteAllOf(ctx, &appsv1.Deployment{}, client.InNamespace(testSecondaryNamespace))).Should(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace(testSecondaryNamespace),
+				client.PropagationPolicy(metav1.DeletePropagationBackground))).Should(Succeed())
+		})
+
+		It("should remove the OSDs of the last step one at a time", func() {
+			const (
+				timeout  = time.Second * 3
+				interval = time.Millisecond * 250
+			)
+			r := newDownscaleReconciler()
+			r.managedOCS.UID = "test-managedocs-uid"
+
+			getOSDReplicas := func(id int) int32 {
+				osd := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("%s-%d", osdAppLabelValue, id), Namespace: testSecondaryNamespace}, osd)).Should(Succeed())
+				if osd.Spec.Replicas == nil {
+					return 1
+				}
+				return *osd.Spec.Replicas
+			}
+			// reconcileUntilJob reconciles until the OSD job with the given name runs for the
+			// given OSD, the job of a previous OSD is deleted first
+			reconcileUntilJob := func(name string, id int) *batchv1.Job {
+				job := &batchv1.Job{}
+				Eventually(func() string {
+					Expect(r.getDownscaleStepCount(desiredCount, desiredCount, replica)).To(Equal(desiredCount))
+					if err := k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: testSecondaryNamespace}, job); err != nil {
+						return ""
+					}
+					return job.Annotations[osdRemovalJobOSDIDsAnnotation]
+				}, timeout, interval).Should(Equal(strconv.Itoa(id)))
+				Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal("test-image"))
+				Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+					corev1.EnvVar{Name: templates.OSDJobIDsEnvVarName, Value: strconv.Itoa(id)}))
+				return job
+			}
+			completeJob := func(job *batchv1.Job) {
+				job.Status.Succeeded = 1
+				Expect(k8sClient.Status().Update(ctx, job)).Should(Succeed())
+			}
+
+			Expect(r.getDownscaleStepCount(currCount, desiredCount, replica)).To(Equal(desiredCount))
+			Expect(r.managedOCS.Status.Downscale.RemovingOSDIDs).To(Equal([]int{3, 4, 5}))
+			Expect(r.isOSDRemovalInProgress()).To(BeTrue())
+
+			for _, id := range []int{3, 4, 5} {
+				By(fmt.Sprintf("marking OSD %d out while it is up", id))
+				outJob := reconcileUntilJob(osdOutJobName, id)
+				Expect(outJob.Spec.Template.Spec.Containers[0].Command).ToNot(BeEmpty())
+				Expect(getOSDReplicas(id)).ToNot(BeZero())
+				completeJob(outJob)
+
+				By(fmt.Sprintf("waiting for the data of OSD %d to be moved", id))
+				metrics.activeClean = false
+				r.managedOCS.Status.Downscale.OSDOutTime = &metav1.Time{Time: time.Now().Add(-osdOutSettleInterval)}
+				Expect(r.getDownscaleStepCount(desiredCount, desiredCount, replica)).To(Equal(desiredCount))
+				Expect(r.managedOCS.Status.Downscale.Message).To(ContainSubstring(fmt.Sprintf("data of OSD %d", id)))
+				Expect(getOSDReplicas(id)).ToNot(BeZero())
+
+				By(fmt.Sprintf("purging OSD %d once it holds no data", id))
+				metrics.activeClean = true
+				removalJob := reconcileUntilJob(osdRemovalJobName, id)
+				Expect(removalJob.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--osd-ids=$(" + templates.OSDJobIDsEnvVarName + ")"))
+				Expect(getOSDReplicas(id)).To(BeZero())
+				for _, other := range []int{3, 4, 5} {
+					if other > id {
+						Expect(getOSDReplicas(other)).ToNot(BeZero())
+					}
+				}
+				completeJob(removalJob)
+
+				// The OSD is removed once Ceph no longer reports it
+				Expect(r.getDownscaleStepCount(desiredCount, desiredCount, replica)).To(Equal(desiredCount))
+				Expect(r.managedOCS.Status.Downscale.RemovingOSDIDs[0]).To(Equal(id))
+				delete(metrics.osdUp, id)
+				Expect(r.getDownscaleStepCount(desiredCount, desiredCount, replica)).To(Equal(desiredCount))
+				Expect(r.managedOCS.Status.Downscale.RemovingOSDIDs).ToNot(ContainElement(id))
+			}
+			Expect(r.isOSDRemovalInProgress()).To(BeFalse())
+			Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleCompleted))
+		})
+		It("should refuse to downscale when the OSDs of the step do not match the device set replica", func() {
+			r := newDownscaleReconciler()
+			Expect(r.getDownscaleStepCount(currCount, desiredCount, replica+1)).To(Equal(currCount))
+			Expect(r.managedOCS.Status.Downscale.State).To(Equal(v1.DownscaleRefused))
+		})
+	})
+})
@@ -0,0 +1,139 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+)
+
+const (
+	// Upper bound on the number of changed fields listed in a drift event
+	maxReportedDriftFields = 10
+
+	// Holds the hash of the spec the operator last applied to an owned resource
+	appliedSpecHashAnnotation = "ocs.openshift.io/applied-spec-hash"
+)
+
+// createOrUpdate wraps ctrl.CreateOrUpdate for resources whose spec is owned by the
+// operator, applying the given reconcile strategy to the mutated spec. When an existing
+// resource is updated while the desired spec is the one the operator last applied, the
+// resource was changed from outside of the operator. The spec fields that differed from
+// the desired state are then reported as an event and counted in the drift metric.
+func (r *ManagedOCSReconciler) createOrUpdate(obj client.Object, strategy v1.ReconcileStrategy, mutate controllerutil.MutateFn) (controllerutil.OperationResult, error) {
+	var live map[string]interface{}
+	var appliedSpecHash, desiredSpecHash string
+	result, err := ctrl.CreateOrUpdate(r.ctx, r.Client, obj, func() error {
+		if obj.GetResourceVersion() != "" {
+			var err error
+			if live, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+				return err
+			}
+			appliedSpecHash = obj.GetAnnotations()[appliedSpecHashAnnotation]
+		}
+		if err := mutate(); err != nil {
+			return err
+		}
+		var err error
+		if desiredSpecHash, err = getSpecHash(obj); err != nil {
+			return err
+		}
+		utils.AddAnnotation(obj, appliedSpecHashAnnotation, desiredSpecHash)
+		return applyReconcileStrategy(obj, live, strategy)
+	})
+	// Updates of the desired spec are made by the operator itself
+	if err != nil || result != controllerutil.OperationResultUpdated || live == nil || appliedSpecHash != desiredSpecHash {
+		return result, err
+	}
+
+	desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return result, err
+	}
+	changedFields := getChangedFields("spec", live["spec"], desired["spec"])
+	if len(changedFields) > 0 {
+		r.reportDrift(obj, changedFields)
+	}
+	return result, nil
+}
+
+// getSpecHash returns a hash of the spec of the given object
+func getSpecHash(obj client.Object) (string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return "", err
+	}
+	// Maps are marshaled with sorted keys, equal specs have the same hash
+	spec, err := json.Marshal(content["spec"])
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(spec)), nil
+}
+
+func (r *ManagedOCSReconciler) reportDrift(obj client.Object, changedFields []string) {
+	kind := reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+
+	r.Log.Info("Reverted drift of owned resource", "kind", kind, "name", obj.GetName(), "fields", changedFields)
+	driftCorrectionsTotal.WithLabelValues(kind).Inc()
+
+	reported := changedFields
+	if len(reported) > maxReportedDriftFields {
+		reported = append(reported[:maxReportedDriftFields:maxReportedDriftFields],
+			fmt.Sprintf("and %d more", len(changedFields)-maxReportedDriftFields))
+	}
+	r.Recorder.Eventf(obj, corev1.EventTypeWarning, eventReasonDriftCorrected,
+		"Reverted changes to %s %s: %s", kind, obj.GetName(), strings.Join(reported, ", "))
+}
+
+// getChangedFields returns the sorted paths of all the fields that differ between the
+// live and desired values. Maps are compared key by key, any other value is compared as a whole.
+func getChangedFields(path string, live, desired interface{}) []string {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if !liveIsMap || !desiredIsMap {
+		if reflect.DeepEqual(live, desired) {
+			return nil
+		}
+		return []string{path}
+	}
+
+	changedFields := []string{}
+	for key, liveValue := range liveMap {
+		changedFields = append(changedFields, getChangedFields(path+"."+key, liveValue, desiredMap[key])...)
+	}
+	for key, desiredValue := range desiredMap {
+		if _, found := liveMap[key]; !found {
+			changedFields = append(changedFields, getChangedFields(path+"."+key, nil, desiredValue)...)
+		}
+	}
+	sort.Strings(changedFields)
+	return changedFields
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	"k8s.io/client-go/tools/record"
+)
+
+var _ = Describe("Drift detection", func() {
+	It("should list the paths of the changed fields", func() {
+		for _, test := range []struct {
+			name     string
+			live     interface{}
+			desired  interface{}
+			expected []string
+		}{{
+			name:     "equal values",
+			live:     map[string]interface{}{"replicas": int64(1), "image": "test"},
+			desired:  map[string]interface{}{"replicas": int64(1), "image": "test"},
+			expected: []string{},
+		}, {
+			name:     "changed value",
+			live:     map[string]interface{}{"replicas": int64(3), "image": "test"},
+			desired:  map[string]interface{}{"replicas": int64(1), "image": "test"},
+			expected: []string{"spec.replicas"},
+		}, {
+			name:     "added and removed fields",
+			live:     map[string]interface{}{"paused": true},
+			desired:  map[string]interface{}{"replicas": int64(1)},
+			expected: []string{"spec.paused", "spec.replicas"},
+		}, {
+			name: "nested fields",
+			live: map[string]interface{}{
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "1", "memory": "1Gi"}},
+			},
+			desired: map[string]interface{}{
+				"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "2", "memory": "1Gi"}},
+			},
+			expected: []string{"spec.resources.limits.cpu"},
+		}, {
+			name:     "lists compared as a whole",
+			live:     map[string]interface{}{"args": []interface{}{"a", "b"}},
+			desired:  map[string]interface{}{"args": []interface{}{"a", "c"}},
+			expected: []string{"spec.args"},
+		}, {
+			name:     "map replaced by another type",
+			live:     map[string]interface{}{"selector": map[string]interface{}{"app": "test"}},
+			desired:  map[string]interface{}{"selector": "app=test"},
+			expected: []string{"spec.selector"},
+		}, {
+			name:     "missing spec",
+			live:     nil,
+			desired:  map[string]interface{}{"replicas": int64(1)},
+			expected: []string{"spec"},
+		}} {
+			By(test.name)
+			Expect(getChangedFields("spec", test.live, test.desired)).To(Equal(test.expected), test.name)
+		}
+	})
+
+	It("should hash the spec of a resource", func() {
+		newPrometheus := func(name string, logLevel string) *promv1.Prometheus {
+			prom := &promv1.Prometheus{}
+			prom.Name = name
+			prom.Spec.LogLevel = logLevel
+			prom.Spec.ExternalLabels = map[string]string{"cluster": "test", "team": "sre"}
+			return prom
+		}
+		hash, err := getSpecHash(newPrometheus("test-prometheus", "info"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hash).ToNot(BeEmpty())
+
+		By("ignoring the metadata")
+		prom := newPrometheus("other-prometheus", "info")
+		utils.AddAnnotation(prom, appliedSpecHashAnnotation, hash)
+		Expect(getSpecHash(prom)).To(Equal(hash))
+
+		By("changing with the spec")
+		Expect(getSpecHash(newPrometheus("test-prometheus", "debug"))).ToNot(Equal(hash))
+	})
+
+	It("should truncate the reported fields", func() {
+		for _, count := range []int{maxReportedDriftFields - 1, maxReportedDriftFields, maxReportedDriftFields + 5} {
+			By(fmt.Sprintf("%d changed fields", count))
+			r := newTestReconciler(utils.NewDefaultAddonParams())
+			recorder := r.Recorder.(*record.FakeRecorder)
+			prom := &promv1.Prometheus{}
+			prom.Name = "test-prometheus"
+
+			changedFields := []string{}
+			for i := 0; i < count; i++ {
+				changedFields = append(changedFields, fmt.Sprintf("spec.field%02d", i))
+			}
+			r.reportDrift(prom, changedFields)
+
+			Expect(recorder.Events).To(HaveLen(1))
+			event := <-recorder.Events
+			Expect(event).To(ContainSubstring(eventReasonDriftCorrected))
+			Expect(event).To(ContainSubstring("Prometheus test-prometheus"))
+			reported := strings.Count(event, "spec.field")
+			if count > maxReportedDriftFields {
+				Expect(reported).To(Equal(maxReportedDriftFields))
+				Expect(event).To(HaveSuffix(fmt.Sprintf("and %d more", count-maxReportedDriftFields)))
+			} else {
+				Expect(reported).To(Equal(count))
+				Expect(event).ToNot(ContainSubstring("more"))
+			}
+			// The caller's list is left untouched
+			Expect(changedFields).To(HaveLen(count))
+		}
+	})
+})
@@ -0,0 +1,55 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons of the events recorded on the ManagedOCS resource
+const (
+	eventReasonFinalizerAdded     = "FinalizerAdded"
+	eventReasonFinalizerRemoved   = "FinalizerRemoved"
+	eventReasonUninstallBlocked   = "UninstallBlocked"
+	eventReasonUninstallStarted   = "UninstallStarted"
+	eventReasonStorageExpanded    = "StorageExpanded"
+	eventReasonDownscaleStep      = "DownscaleStep"
+	eventReasonDownscaleRefused   = "DownscaleRefused"
+	eventReasonDownscaleCompleted = "DownscaleCompleted"
+	eventReasonMCGEnabled         = "MCGEnabled"
+
+	eventReasonAlertReceiverInvalid = "AlertReceiverInvalid"
+
+	eventReasonForceUninstallAccepted  = "ForceUninstallAccepted"
+	eventReasonForceUninstallScheduled = "ForceUninstallScheduled"
+	eventReasonForceUninstallRejected  = "ForceUninstallRejected"
+	eventReasonForceUninstallCancelled = "ForceUninstallCancelled"
+	eventReasonForceUninstallDeleting  = "ForceUninstallDeletingResources"
+
+	// Recorded on the operator owned resources whose spec was reverted
+	eventReasonDriftCorrected = "DriftCorrected"
+)
+
+// recordEvent records a Normal event on the ManagedOCS resource
+func (r *ManagedOCSReconciler) recordEvent(reason string, messageFmt string, args ...interface{}) {
+	r.Recorder.Eventf(r.managedOCS, corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+// recordWarning records a Warning event on the ManagedOCS resource
+func (r *ManagedOCSReconciler) recordWarning(reason string, messageFmt string, args ...interface{}) {
+	r.Recorder.Eventf(r.managedOCS, corev1.EventTypeWarning, reason, messageFmt, args...)
+}
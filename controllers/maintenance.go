@@ -0,0 +1,66 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// getActiveMaintenanceWindows returns the maintenance windows that are active now. A requeue
+// is requested for the next time one of the windows starts or ends, so the silences are
+// applied and removed on time. Invalid windows are ignored.
+func (r *ManagedOCSReconciler) getActiveMaintenanceWindows() []v1.MaintenanceWindow {
+	now := time.Now()
+	active := []v1.MaintenanceWindow{}
+	for i := range r.managedOCS.Spec.MaintenanceWindows {
+		window := &r.managedOCS.Spec.MaintenanceWindows[i]
+		if errs := window.Validate(field.NewPath("spec", "maintenanceWindows").Index(i)); len(errs) > 0 {
+			r.Log.V(-1).Info("Ignoring invalid maintenance window", "error", errs.ToAggregate().Error())
+			continue
+		}
+		switch {
+		case window.IsActive(now):
+			active = append(active, *window.DeepCopy())
+			r.requestRequeueAfter(window.End.Sub(now))
+		case now.Before(window.Start.Time):
+			r.requestRequeueAfter(window.Start.Sub(now))
+		}
+	}
+	return active
+}
+
+// getMaintenanceWindowMatchers returns the alertmanager matchers of each of the given windows.
+// Windows may match any alert, the DeadMansSnitch routes are kept ahead of the silences.
+func getMaintenanceWindowMatchers(windows []v1.MaintenanceWindow) [][]promv1a1.Matcher {
+	matcherSets := [][]promv1a1.Matcher{}
+	for _, window := range windows {
+		matchers := []promv1a1.Matcher{}
+		for _, matcher := range window.Matchers {
+			matchers = append(matchers, promv1a1.Matcher{
+				Name:  matcher.Name,
+				Value: matcher.Value,
+				Regex: matcher.Regex,
+			})
+		}
+		matcherSets = append(matcherSets, matchers)
+	}
+	return matcherSets
+}
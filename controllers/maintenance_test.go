@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("DeadMansSnitch silencing", func() {
+	// getFirstMatchingReceiver returns the receiver of the first route matching the alert,
+	// the way alertmanager evaluates the routes of the config
+	getFirstMatchingReceiver := func(config *promv1a1.AlertmanagerConfig, alertName string) string {
+		for _, raw := range config.Spec.Route.Routes {
+			route := promv1a1.Route{}
+			Expect(json.Unmarshal(raw.Raw, &route)).Should(Succeed())
+			matched := true
+			for _, matcher := range route.Matchers {
+				Expect(matcher.Name).To(Equal("alertname"))
+				if matcher.Regex {
+					matched = matched && regexp.MustCompile("^(?:"+matcher.Value+")$").MatchString(alertName)
+				} else {
+					matched = matched && matcher.Value == alertName
+				}
+			}
+			if matched && !route.Continue {
+				return route.Receiver
+			}
+		}
+		return config.Spec.Route.Receiver
+	}
+
+	It("should route the heartbeat ahead of the routing policy silences", func() {
+		policy := templates.DefaultAlertRoutingPolicy.Merge(&templates.AlertRoutingPolicy{Silences: []string{".*"}})
+		Expect(policy.Validate()).Should(Succeed())
+		config := templates.NewAlertmanagerConfig(policy)
+		Expect(getFirstMatchingReceiver(&config, templates.DeadMansSnitchAlertName)).To(Equal(templates.DeadMansSnitchReceiverName))
+		Expect(getFirstMatchingReceiver(&config, "CephClusterErrorState")).To(Equal("null"))
+	})
+	It("should reject a routing policy that does not route the heartbeat", func() {
+		policy := templates.DefaultAlertRoutingPolicy.Merge(&templates.AlertRoutingPolicy{
+			Routes: []templates.AlertRoutingRule{{
+				Receiver: templates.DeadMansSnitchReceiverName,
+				Alerts:   []string{"Watchdog"},
+			}},
+		})
+		Expect(policy.Validate()).ShouldNot(Succeed())
+	})
+	It("should route the heartbeat ahead of a maintenance window matching all alerts", func() {
+		now := time.Now()
+		windows := []v1.MaintenanceWindow{{
+			Start: metav1.NewTime(now.Add(-time.Hour)),
+			End:   metav1.NewTime(now.Add(time.Hour)),
+		}, {
+			Start:    metav1.NewTime(now.Add(-time.Hour)),
+			End:      metav1.NewTime(now.Add(time.Hour)),
+			Matchers: []v1.AlertMatcher{{Name: "alertname", Value: "Dead.*", Regex: true}},
+		}}
+		config := templates.NewAlertmanagerConfig(&templates.DefaultAlertRoutingPolicy)
+		Expect(templates.SilenceAlerts(&config, getMaintenanceWindowMatchers(windows))).Should(Succeed())
+		Expect(getFirstMatchingReceiver(&config, templates.DeadMansSnitchAlertName)).To(Equal(templates.DeadMansSnitchReceiverName))
+		Expect(getFirstMatchingReceiver(&config, "CephClusterErrorState")).To(Equal("null"))
+	})
+})
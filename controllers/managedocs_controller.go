@@ -20,9 +20,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/url"
-	"strconv"
+	"sort"
 	"strings"
 	"time"
 
@@ -32,11 +31,14 @@ import (
 	opv1a1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -68,9 +70,7 @@ const (
 	alertmanagerName                       = "managed-ocs-alertmanager"
 	alertmanagerConfigName                 = "managed-ocs-alertmanager-config"
 	dmsRuleName                            = "dms-monitor-rule"
-	storageClassSizeKey                    = "size"
-	enableMCGKey                           = "enable-mcg"
-	notificationEmailKeyPrefix             = "notification-email"
+	deployerRuleName                       = "deployer-monitor-rule"
 	deviceSetName                          = "default"
 	storageClassRbdName                    = "ocs-storagecluster-ceph-rbd"
 	storageClassCephFSName                 = "ocs-storagecluster-cephfs"
@@ -80,6 +80,7 @@ const (
 	egressNetworkPolicyName                = "egress-rule"
 	ingressNetworkPolicyName               = "ingress-rule"
 	cephIngressNetworkPolicyName           = "ceph-ingress-rule"
+	providerIngressNetworkPolicyName       = "provider-ingress-rule"
 	monLabelKey                            = "app"
 	monLabelValue                          = "managed-ocs"
 	rookConfigMapName                      = "rook-ceph-operator-config"
@@ -87,9 +88,20 @@ const (
 	grafanaDatasourceSecretName            = "grafana-datasources"
 	grafanaDatasourceSecretKey             = "prometheus.yaml"
 	k8sMetricsServiceMonitorAuthSecretName = "k8s-metrics-service-monitor-auth"
+	deployerMetricsServiceMonitorName      = "deployer-metrics-service-monitor"
 	openshiftMonitoringNamespace           = "openshift-monitoring"
 	alertRelabelConfigSecretName           = "managed-ocs-alert-relabel-config-secret"
 	alertRelabelConfigSecretKey            = "alertrelabelconfig.yaml"
+	alertRoutingPolicyConfigMapName        = "managed-ocs-alert-routing-policy"
+	alertRoutingPolicyConfigMapKey         = "policy.yaml"
+)
+
+// Keys of the pagerduty secret. One of the service key, used by the legacy Events API,
+// or the Events API v2 routing key must be set.
+const (
+	pagerdutyServiceKeyKey = "PAGERDUTY_KEY"
+	pagerdutyRoutingKeyKey = "ROUTING_KEY"
+	pagerdutyClusterIDKey  = "CLUSTER_ID"
 )
 
 // ManagedOCSReconciler reconciles a ManagedOCS object
@@ -98,17 +110,27 @@ type ManagedOCSReconciler struct {
 	UnrestrictedClient client.Client
 	Log                logr.Logger
 	Scheme             *runtime.Scheme
+	Recorder           record.EventRecorder
 
+	AddonName                    string
+	ClusterID                    string
+	ClusterName                  string
 	AddonParamSecretName         string
 	AddonConfigMapName           string
 	AddonConfigMapDeleteLabelKey string
 	PagerdutySecretName          string
 	DeadMansSnitchSecretName     string
 	SMTPSecretName               string
+	OpsgenieSecretName           string
+	SlackSecretName              string
+	WebhookSecretName            string
 	SOPEndpoint                  string
 	AlertSMTPFrom                string
-	CustomerNotificationHTMLPath string
+	CustomerNotification         *templates.CustomerNotification
 	DeploymentType               string
+	DownscaleMaxUsageRatio       float64
+	DMSHeartbeatInterval         time.Duration
+	ForceUninstallGracePeriod    time.Duration
 
 	ctx                                context.Context
 	managedOCS                         *v1.ManagedOCS
@@ -116,19 +138,41 @@ type ManagedOCSReconciler struct {
 	egressNetworkPolicy                *openshiftv1.EgressNetworkPolicy
 	ingressNetworkPolicy               *netv1.NetworkPolicy
 	cephIngressNetworkPolicy           *netv1.NetworkPolicy
+	providerIngressNetworkPolicy       *netv1.NetworkPolicy
 	prometheus                         *promv1.Prometheus
 	dmsRule                            *promv1.PrometheusRule
+	deployerRule                       *promv1.PrometheusRule
 	alertmanager                       *promv1.Alertmanager
 	addonParamSecret                   *corev1.Secret
+	addonParams                        *utils.AddonParams
+	addonParamsErrs                    field.ErrorList
 	pagerdutySecret                    *corev1.Secret
 	deadMansSnitchSecret               *corev1.Secret
 	smtpSecret                         *corev1.Secret
+	opsgenieSecret                     *corev1.Secret
+	slackSecret                        *corev1.Secret
+	webhookSecret                      *corev1.Secret
 	alertmanagerConfig                 *promv1a1.AlertmanagerConfig
 	alertRelabelConfigSecret           *corev1.Secret
+	alertRoutingPolicyConfigMap        *corev1.ConfigMap
+	alertRoutingPolicy                 *templates.AlertRoutingPolicy
 	k8sMetricsServiceMonitor           *promv1.ServiceMonitor
 	k8sMetricsServiceMonitorAuthSecret *corev1.Secret
+	deployerMetricsServiceMonitor      *promv1.ServiceMonitor
 	namespace                          string
 	reconcileStrategy                  v1.ReconcileStrategy
+	reconcileStrategies                v1.ComponentReconcileStrategyMap
+	deploymentProfile                  *deploymentProfile
+	cloudProvider                      *cloudProvider
+	cephMetrics                        cephMetricsProvider
+	alertmanagerMetrics                alertmanagerMetricsProvider
+	alertmanagerAlerts                 alertmanagerAlertsProvider
+	dmsDeliveries                      float64
+	dmsLastDeliveryTime                time.Time
+	defaultAlertRoutingPolicy          *templates.AlertRoutingPolicy
+	requeueAfter                       time.Duration
+	clusterIdentity                    *clusterIdentity
+	clusterIdentityExpiry              time.Time
 }
 
 // Add necessary rbac permissions for managedocs finalizer in order to set blockOwnerDeletion.
@@ -141,21 +185,55 @@ type ManagedOCSReconciler struct {
 // +kubebuilder:rbac:groups="monitoring.coreos.com",namespace=system,resources=podmonitors,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups="monitoring.coreos.com",namespace=system,resources=servicemonitors,verbs=get;list;watch;update;patch;create;delete
 // +kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=create;get;list;watch;update
+// +kubebuilder:rbac:groups="",namespace=system,resources=configmaps,verbs=create;get;list;watch;update
 // +kubebuilder:rbac:groups=operators.coreos.com,namespace=system,resources=clusterserviceversions,verbs=get;list;watch;delete;update;patch
-// +kubebuilder:rbac:groups="apps",namespace=system,resources=statefulsets,verbs=get;list;watch
-// +kubebuilder:rbac:groups="",resources={persistentvolumeclaims,secrets},verbs=get;list;watch
+// +kubebuilder:rbac:groups="apps",namespace=system,resources={deployments,statefulsets},verbs=get;list;watch
+// +kubebuilder:rbac:groups="apps",namespace=system,resources=deployments,verbs=update
+// +kubebuilder:rbac:groups="batch",namespace=system,resources=jobs,verbs=create;get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources={persistentvolumeclaims,persistentvolumes,secrets},verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources={persistentvolumeclaims,persistentvolumes},verbs=delete
+// +kubebuilder:rbac:groups="snapshot.storage.k8s.io",resources=volumesnapshots,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups="snapshot.storage.k8s.io",resources=volumesnapshotclasses,verbs=get;list;watch
+// +kubebuilder:rbac:groups="objectbucket.io",resources=objectbucketclaims,verbs=get;list;watch;delete
 // +kubebuilder:rbac:groups="storage.k8s.io",resources=storageclass,verbs=get;list;watch
-// +kubebuilder:rbac:groups="networking.k8s.io",namespace=system,resources=networkpolicies,verbs=create;get;list;watch;update
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="config.openshift.io",resources={clusterversions,infrastructures},verbs=get
+// +kubebuilder:rbac:groups="networking.k8s.io",namespace=system,resources=networkpolicies,verbs=create;get;list;watch;update;delete
 // +kubebuilder:rbac:groups="network.openshift.io",namespace=system,resources=egressnetworkpolicies,verbs=create;get;list;watch;update
 // +kubebuilder:rbac:groups="coordination.k8s.io",namespace=system,resources=leases,verbs=create;get;list;watch;update
+// +kubebuilder:rbac:groups="",namespace=system,resources=events,verbs=create;patch
 
 // SetupWithManager creates an setup a ManagedOCSReconciler to work with the provided manager
 func (r *ManagedOCSReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	profile, found := deploymentProfiles[strings.ToLower(r.DeploymentType)]
+	if !found {
+		return fmt.Errorf("Invalid deployment type value: %v", r.DeploymentType)
+	}
+	r.deploymentProfile = profile
+
+	if r.DMSHeartbeatInterval <= 0 {
+		r.DMSHeartbeatInterval = defaultDMSHeartbeatInterval
+	}
+	if r.ForceUninstallGracePeriod <= 0 {
+		r.ForceUninstallGracePeriod = defaultForceUninstallGracePeriod
+	}
+	if r.DownscaleMaxUsageRatio == 0 {
+		r.DownscaleMaxUsageRatio = defaultDownscaleMaxUsageRatio
+	}
+	if r.DownscaleMaxUsageRatio < 0 || r.DownscaleMaxUsageRatio > 1 {
+		return fmt.Errorf("Invalid downscale max usage ratio value: %v", r.DownscaleMaxUsageRatio)
+	}
+	r.defaultAlertRoutingPolicy = profile.alertRoutingPolicy.Merge(r.getDMSHeartbeatPolicyOverrides())
+
 	ctrlOptions := controller.Options{
 		MaxConcurrentReconciles: 1,
 	}
+	// Annotation changes carry force uninstall requests
 	managedOCSPredicates := builder.WithPredicates(
-		predicate.GenerationChangedPredicate{},
+		predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+		),
 	)
 	secretPredicates := builder.WithPredicates(
 		predicate.NewPredicateFuncs(
@@ -164,7 +242,10 @@ func (r *ManagedOCSReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				return name == r.AddonParamSecretName ||
 					name == r.PagerdutySecretName ||
 					name == r.DeadMansSnitchSecretName ||
-					name == r.SMTPSecretName
+					name == r.SMTPSecretName ||
+					name == r.OpsgenieSecretName ||
+					name == r.SlackSecretName ||
+					name == r.WebhookSecretName
 			},
 		),
 	)
@@ -176,7 +257,7 @@ func (r *ManagedOCSReconciler) SetupWithManager(mgr ctrl.Manager) error {
 					if _, ok := client.GetLabels()[r.AddonConfigMapDeleteLabelKey]; ok {
 						return true
 					}
-				} else if name == rookConfigMapName {
+				} else if name == rookConfigMapName || name == alertRoutingPolicyConfigMapName {
 					return true
 				}
 				return false
@@ -328,6 +409,7 @@ func (r *ManagedOCSReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 func (r *ManagedOCSReconciler) initReconciler(ctx context.Context, req ctrl.Request) {
 	r.ctx = ctx
 	r.namespace = req.NamespacedName.Namespace
+	r.requeueAfter = 0
 
 	r.managedOCS = &v1.ManagedOCS{}
 	r.managedOCS.Name = req.NamespacedName.Name
@@ -349,6 +431,10 @@ func (r *ManagedOCSReconciler) initReconciler(ctx context.Context, req ctrl.Requ
 	r.cephIngressNetworkPolicy.Name = cephIngressNetworkPolicyName
 	r.cephIngressNetworkPolicy.Namespace = r.namespace
 
+	r.providerIngressNetworkPolicy = &netv1.NetworkPolicy{}
+	r.providerIngressNetworkPolicy.Name = providerIngressNetworkPolicyName
+	r.providerIngressNetworkPolicy.Namespace = r.namespace
+
 	r.prometheus = &promv1.Prometheus{}
 	r.prometheus.Name = prometheusName
 	r.prometheus.Namespace = r.namespace
@@ -357,6 +443,10 @@ func (r *ManagedOCSReconciler) initReconciler(ctx context.Context, req ctrl.Requ
 	r.dmsRule.Name = dmsRuleName
 	r.dmsRule.Namespace = r.namespace
 
+	r.deployerRule = &promv1.PrometheusRule{}
+	r.deployerRule.Name = deployerRuleName
+	r.deployerRule.Namespace = r.namespace
+
 	r.alertmanager = &promv1.Alertmanager{}
 	r.alertmanager.Name = alertmanagerName
 	r.alertmanager.Namespace = r.namespace
@@ -373,6 +463,18 @@ func (r *ManagedOCSReconciler) initReconciler(ctx context.Context, req ctrl.Requ
 	r.smtpSecret.Name = r.SMTPSecretName
 	r.smtpSecret.Namespace = r.namespace
 
+	r.opsgenieSecret = &corev1.Secret{}
+	r.opsgenieSecret.Name = r.OpsgenieSecretName
+	r.opsgenieSecret.Namespace = r.namespace
+
+	r.slackSecret = &corev1.Secret{}
+	r.slackSecret.Name = r.SlackSecretName
+	r.slackSecret.Namespace = r.namespace
+
+	r.webhookSecret = &corev1.Secret{}
+	r.webhookSecret.Name = r.WebhookSecretName
+	r.webhookSecret.Namespace = r.namespace
+
 	r.deadMansSnitchSecret = &corev1.Secret{}
 	r.deadMansSnitchSecret.Name = r.DeadMansSnitchSecretName
 	r.deadMansSnitchSecret.Namespace = r.namespace
@@ -385,6 +487,10 @@ func (r *ManagedOCSReconciler) initReconciler(ctx context.Context, req ctrl.Requ
 	r.k8sMetricsServiceMonitor.Name = k8sMetricsServiceMonitorName
 	r.k8sMetricsServiceMonitor.Namespace = r.namespace
 
+	r.deployerMetricsServiceMonitor = &promv1.ServiceMonitor{}
+	r.deployerMetricsServiceMonitor.Name = deployerMetricsServiceMonitorName
+	r.deployerMetricsServiceMonitor.Namespace = r.namespace
+
 	r.k8sMetricsServiceMonitorAuthSecret = &corev1.Secret{}
 	r.k8sMetricsServiceMonitorAuthSecret.Name = k8sMetricsServiceMonitorAuthSecretName
 	r.k8sMetricsServiceMonitorAuthSecret.Namespace = r.namespace
@@ -393,6 +499,11 @@ func (r *ManagedOCSReconciler) initReconciler(ctx context.Context, req ctrl.Requ
 	r.alertRelabelConfigSecret.Name = alertRelabelConfigSecretName
 	r.alertRelabelConfigSecret.Namespace = r.namespace
 
+	r.alertRoutingPolicyConfigMap = &corev1.ConfigMap{}
+	r.alertRoutingPolicyConfigMap.Name = alertRoutingPolicyConfigMapName
+	r.alertRoutingPolicyConfigMap.Namespace = r.namespace
+	r.alertRoutingPolicy = r.defaultAlertRoutingPolicy
+
 }
 
 func (r *ManagedOCSReconciler) reconcilePhases() (reconcile.Result, error) {
@@ -405,17 +516,25 @@ func (r *ManagedOCSReconciler) reconcilePhases() (reconcile.Result, error) {
 
 	if !r.managedOCS.DeletionTimestamp.IsZero() {
 		if r.verifyComponentsDoNotExist() {
+			// The status cannot be updated once the finalizer is removed
+			r.setUninstallPhase(v1.UninstallRemovingCSV, "Removing the deployer CSV")
+			if err := r.Client.Status().Update(r.ctx, r.managedOCS); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to update managedOCS status: %v", err)
+			}
+
 			r.Log.Info("removing finalizer from the ManagedOCS resource")
 			r.managedOCS.SetFinalizers(utils.Remove(r.managedOCS.GetFinalizers(), ManagedOCSFinalizer))
 			if err := r.Client.Update(r.ctx, r.managedOCS); err != nil {
 				return ctrl.Result{}, fmt.Errorf("failed to remove finalizer from managedOCS: %v", err)
 			}
 			r.Log.Info("finallizer removed successfully")
+			r.recordEvent(eventReasonFinalizerRemoved, "Removed finalizer %s, all components were removed", ManagedOCSFinalizer)
 
 		} else {
 			// Storage cluster needs to be deleted before we delete the CSV so we can not leave it to the
 			// k8s garbage collector to delete it
 			r.Log.Info("deleting storagecluster")
+			r.setUninstallPhase(v1.UninstallDeletingStorageCluster, "Deleting the storage cluster")
 			if err := r.delete(r.storageCluster); err != nil {
 				return ctrl.Result{}, fmt.Errorf("unable to delete storagecluster: %v", err)
 			}
@@ -428,90 +547,92 @@ func (r *ManagedOCSReconciler) reconcilePhases() (reconcile.Result, error) {
 			if err := r.update(r.managedOCS); err != nil {
 				return ctrl.Result{}, fmt.Errorf("failed to update managedOCS with finalizer: %v", err)
 			}
+			r.recordEvent(eventReasonFinalizerAdded, "Added finalizer %s", ManagedOCSFinalizer)
 		}
 
 		// Find the effective reconcile strategy
-		r.reconcileStrategy = v1.ReconcileStrategyStrict
-		if strings.EqualFold(string(r.managedOCS.Spec.ReconcileStrategy), string(v1.ReconcileStrategyNone)) {
-			r.reconcileStrategy = v1.ReconcileStrategyNone
+		strategy, err := v1.ParseReconcileStrategy(string(r.managedOCS.Spec.ReconcileStrategy))
+		if err != nil {
+			r.Log.V(-1).Info("Unknown reconcile strategy, falling back to strict", "reconcileStrategy", r.managedOCS.Spec.ReconcileStrategy)
+			strategy = v1.ReconcileStrategyStrict
 		}
+		r.reconcileStrategy = strategy
+		r.reconcileStrategies = r.getEffectiveReconcileStrategies()
 
 		if err := r.get(r.addonParamSecret); err != nil {
 			return ctrl.Result{}, fmt.Errorf("Failed to get the addon param secret, Secret Name: %v", r.AddonParamSecretName)
 		}
-
-		// Reconcile the different resources
-		if err := r.reconcileRookCephOperatorConfig(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileStorageCluster(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileCSV(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileAlertRelabelConfigSecret(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcilePrometheus(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileAlertmanager(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileAlertmanagerConfig(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileK8SMetricsServiceMonitorAuthSecret(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileK8SMetricsServiceMonitor(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileMonitoringResources(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileDMSPrometheusRule(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileOCSInitialization(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileEgressNetworkPolicy(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileIngressNetworkPolicy(); err != nil {
-			return ctrl.Result{}, err
-		}
-		if err := r.reconcileCephIngressNetworkPolicy(); err != nil {
-			return ctrl.Result{}, err
+		r.addonParams, r.addonParamsErrs = utils.ParseAddonParams(r.addonParamSecret.Data)
+		if len(r.addonParamsErrs) > 0 {
+			r.Log.V(-1).Info("Add-on parameters secret contains invalid values", "errors", r.addonParamsErrs.ToAggregate().Error())
 		}
 
+		// Reconcile the different resources. Failures are collected so that independent
+		// phases still get reconciled, and are returned as a combined error for requeue
+		phasesErr := r.runReconcilePhases(r.getReconcilePhases())
+
 		r.managedOCS.Status.ReconcileStrategy = r.reconcileStrategy
+		r.managedOCS.Status.ComponentReconcileStrategies = r.reconcileStrategies
 
 		// Check if we need and can uninstall
-		if initiateUninstall && r.areComponentsReadyForUninstall() {
-			found, err := r.findOCSVolumeClaims()
+		if !initiateUninstall {
+			setUninstallBlockingResourcesMetric(nil)
+			forceUninstallDeletionTime.Set(0)
+			r.managedOCS.Status.Uninstall = nil
+			r.setCondition(v1.ConditionUninstallBlocked, metav1.ConditionFalse, "UninstallNotRequested", "Uninstall was not requested")
+		} else if !r.areComponentsReadyForUninstall() {
+			r.setUninstallPhase(v1.UninstallRequested, "Waiting for all components to be ready before uninstalling")
+			r.setCondition(v1.ConditionUninstallBlocked, metav1.ConditionTrue, "ComponentsNotReady", "Waiting for all components to be ready before uninstalling")
+		} else {
+			blockers, err := r.findUninstallBlockers()
 			if err != nil {
-				return ctrl.Result{}, err
+				return ctrl.Result{}, utilerrors.NewAggregate([]error{phasesErr, err})
+			}
+			setUninstallBlockingResourcesMetric(blockers)
+			if len(blockers) == 0 {
+				forceUninstallDeletionTime.Set(0)
 			}
-			if found {
-				r.Log.Info("Found consumer PVCs using OCS storageclasses, cannot proceed on uninstallation")
-				return ctrl.Result{Requeue: true, RequeueAfter: 10 * time.Second}, nil
+			if len(blockers) > 0 {
+				r.Log.Info("Found consumer resources using OCS storage, cannot proceed on uninstallation", "count", len(blockers))
+				requeueAfter := r.reportBlockingResources(blockers)
+				forceRequeueAfter, err := r.reconcileForceUninstall(r.managedOCS.Status.Uninstall, blockers)
+				if err != nil {
+					return ctrl.Result{}, utilerrors.NewAggregate([]error{phasesErr, err})
+				}
+				if forceRequeueAfter > 0 && forceRequeueAfter < requeueAfter {
+					requeueAfter = forceRequeueAfter
+				}
+				r.setCondition(
+					v1.ConditionUninstallBlocked,
+					metav1.ConditionTrue,
+					"ConsumerResourcesFound",
+					fmt.Sprintf("Found %d consumer resources using OCS storage", len(blockers)),
+				)
+				return ctrl.Result{RequeueAfter: requeueAfter}, phasesErr
 			}
 
 			r.Log.Info("starting OCS uninstallation - deleting managedocs")
+			r.recordEvent(eventReasonUninstallStarted, "Uninstall started, deleting the ManagedOCS resource")
+			r.setCondition(v1.ConditionUninstallBlocked, metav1.ConditionFalse, "UninstallInProgress", "Uninstall is in progress")
 			if err := r.delete(r.managedOCS); err != nil {
-				return ctrl.Result{}, fmt.Errorf("unable to delete managedocs: %v", err)
+				return ctrl.Result{}, utilerrors.NewAggregate([]error{phasesErr, fmt.Errorf("unable to delete managedocs: %v", err)})
 			}
 			// Refreshing local managedOCS object after deletion is scheduled
 			// to avoid conflict while updating status
 			if err := r.get(r.managedOCS); err != nil {
 				if !errors.IsNotFound(err) {
-					return ctrl.Result{}, err
+					return ctrl.Result{}, utilerrors.NewAggregate([]error{phasesErr, err})
 				}
 				r.Log.V(-1).Info("Trying to reload ManagedOCS resource after delete failed, ManagedOCS resource not found")
 			}
+			r.setUninstallPhase(v1.UninstallDeletingStorageCluster, "Deleting the ManagedOCS resource")
+		}
+
+		if phasesErr != nil {
+			return ctrl.Result{}, phasesErr
+		}
+		if r.requeueAfter > 0 {
+			return ctrl.Result{RequeueAfter: r.requeueAfter}, nil
 		}
 
 	} else if initiateUninstall {
@@ -521,6 +642,128 @@ func (r *ManagedOCSReconciler) reconcilePhases() (reconcile.Result, error) {
 	return ctrl.Result{}, nil
 }
 
+// reconcilePhase describes a single step of the reconcile loop. A phase is skipped
+// when any of the phases listed in dependsOn did not succeed during the same reconcile.
+// When condition is set, that ManagedOCS condition reflects the outcome of the phase.
+type reconcilePhase struct {
+	name      string
+	reconcile func() error
+	dependsOn []string
+	condition string
+}
+
+func (r *ManagedOCSReconciler) getReconcilePhases() []reconcilePhase {
+	return []reconcilePhase{
+		{name: "RookCephOperatorConfig", reconcile: r.reconcileRookCephOperatorConfig},
+		// CSI resource limits have to be in place before the storage cluster deploys the CSI pods
+		{name: "StorageCluster", reconcile: r.reconcileStorageCluster, dependsOn: []string{"RookCephOperatorConfig"}},
+		{name: "CSV", reconcile: r.reconcileCSV},
+		{name: "AlertRelabelConfigSecret", reconcile: r.reconcileAlertRelabelConfigSecret},
+		{name: "Prometheus", reconcile: r.reconcilePrometheus, dependsOn: []string{"AlertRelabelConfigSecret"}},
+		{name: "Alertmanager", reconcile: r.reconcileAlertmanager},
+		{name: "AlertRoutingPolicy", reconcile: r.reconcileAlertRoutingPolicy},
+		{name: "AlertmanagerConfig", reconcile: r.reconcileAlertmanagerConfig, condition: v1.ConditionAlertingConfigured},
+		{name: "K8SMetricsServiceMonitorAuthSecret", reconcile: r.reconcileK8SMetricsServiceMonitorAuthSecret},
+		{name: "K8SMetricsServiceMonitor", reconcile: r.reconcileK8SMetricsServiceMonitor, dependsOn: []string{"K8SMetricsServiceMonitorAuthSecret"}},
+		{name: "DeployerMetricsServiceMonitor", reconcile: r.reconcileDeployerMetricsServiceMonitor},
+		{name: "MonitoringResources", reconcile: r.reconcileMonitoringResources},
+		{name: "DMSPrometheusRule", reconcile: r.reconcileDMSPrometheusRule},
+		{name: "DeployerPrometheusRule", reconcile: r.reconcileDeployerPrometheusRule},
+		{name: "DMSHeartbeat", reconcile: r.reconcileDMSHeartbeat, dependsOn: []string{"AlertmanagerConfig", "DMSPrometheusRule"}, condition: v1.ConditionHeartbeatHealthy},
+		{name: "OCSInitialization", reconcile: r.reconcileOCSInitialization},
+		{name: "EgressNetworkPolicy", reconcile: r.reconcileEgressNetworkPolicy},
+		{name: "IngressNetworkPolicy", reconcile: r.reconcileIngressNetworkPolicy},
+		{name: "CephIngressNetworkPolicy", reconcile: r.reconcileCephIngressNetworkPolicy},
+		{name: "ProviderIngressNetworkPolicy", reconcile: r.reconcileProviderIngressNetworkPolicy},
+	}
+}
+
+// runReconcilePhases runs the given phases in order without stopping on the first failure.
+// The outcome of every phase is recorded in the ManagedOCS status and the errors of all
+// failed phases are returned as a single aggregated error.
+func (r *ManagedOCSReconciler) runReconcilePhases(phases []reconcilePhase) error {
+	succeeded := map[string]bool{}
+	phaseStatuses := []v1.ReconcilePhaseStatus{}
+	failedPhases := []string{}
+	errs := []error{}
+
+	for _, phase := range phases {
+		phaseStatus := v1.ReconcilePhaseStatus{Name: phase.name}
+
+		unmetDependencies := []string{}
+		for _, dependency := range phase.dependsOn {
+			if !succeeded[dependency] {
+				unmetDependencies = append(unmetDependencies, dependency)
+			}
+		}
+
+		if len(unmetDependencies) > 0 {
+			r.Log.Info("Skipping reconcile phase", "Phase", phase.name, "UnmetDependencies", unmetDependencies)
+			phaseStatus.State = v1.ReconcilePhaseSkipped
+			phaseStatus.Message = fmt.Sprintf("Depends on unsuccessful phases: %s", strings.Join(unmetDependencies, ", "))
+			if phase.condition != "" {
+				r.setCondition(phase.condition, metav1.ConditionUnknown, fmt.Sprintf("%sReconcileSkipped", phase.name), phaseStatus.Message)
+			}
+
+		} else if err := r.runReconcilePhase(phase); err != nil {
+			r.Log.Error(err, "Reconcile phase failed", "Phase", phase.name)
+			phaseStatus.State = v1.ReconcilePhaseFailed
+			phaseStatus.Message = err.Error()
+			failedPhases = append(failedPhases, phase.name)
+			errs = append(errs, fmt.Errorf("%s: %v", phase.name, err))
+			if phase.condition != "" {
+				r.setCondition(phase.condition, metav1.ConditionFalse, fmt.Sprintf("%sReconcileFailed", phase.name), err.Error())
+			}
+
+		} else {
+			phaseStatus.State = v1.ReconcilePhaseSucceeded
+			succeeded[phase.name] = true
+			if phase.condition != "" {
+				r.setCondition(
+					phase.condition,
+					metav1.ConditionTrue,
+					fmt.Sprintf("%sReconciled", phase.name),
+					fmt.Sprintf("%s reconciled successfully", phase.name),
+				)
+			}
+		}
+
+		phaseStatuses = append(phaseStatuses, phaseStatus)
+	}
+	r.managedOCS.Status.ReconcilePhases = phaseStatuses
+
+	switch len(failedPhases) {
+	case 0:
+		r.setCondition(v1.ConditionDegraded, metav1.ConditionFalse, "ReconcileSucceeded", "All reconcile phases completed successfully")
+	case 1:
+		r.setCondition(v1.ConditionDegraded, metav1.ConditionTrue, fmt.Sprintf("%sReconcileFailed", failedPhases[0]), phaseStatusMessage(phaseStatuses, failedPhases[0]))
+	default:
+		r.setCondition(v1.ConditionDegraded, metav1.ConditionTrue, "MultiplePhasesFailed", utilerrors.NewAggregate(errs).Error())
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// runReconcilePhase runs a single reconcile phase and records its duration and failures
+func (r *ManagedOCSReconciler) runReconcilePhase(phase reconcilePhase) error {
+	start := time.Now()
+	err := phase.reconcile()
+	reconcilePhaseDuration.WithLabelValues(phase.name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		reconcilePhaseErrorsTotal.WithLabelValues(phase.name).Inc()
+	}
+	return err
+}
+
+func phaseStatusMessage(phaseStatuses []v1.ReconcilePhaseStatus, name string) string {
+	for i := range phaseStatuses {
+		if phaseStatuses[i].Name == name {
+			return phaseStatuses[i].Message
+		}
+	}
+	return ""
+}
+
 func (r *ManagedOCSReconciler) updateComponentStatus() {
 	// Getting the status of the StorageCluster component.
 	scStatus := &r.managedOCS.Status.Components.StorageCluster
@@ -530,8 +773,10 @@ func (r *ManagedOCSReconciler) updateComponentStatus() {
 		} else {
 			scStatus.State = v1.ComponentPending
 		}
+		storageDeviceSetCount.WithLabelValues("actual").Set(float64(getDeviceSetCount(r.storageCluster)))
 	} else if errors.IsNotFound(err) {
 		scStatus.State = v1.ComponentNotFound
+		storageDeviceSetCount.WithLabelValues("actual").Set(0)
 	} else {
 		r.Log.V(-1).Info("error getting StorageCluster, setting compoment status to Unknown")
 		scStatus.State = v1.ComponentUnknown
@@ -588,6 +833,49 @@ func (r *ManagedOCSReconciler) updateComponentStatus() {
 		r.Log.V(-1).Info("error getting Alertmanager, setting compoment status to Unknown")
 		amStatus.State = v1.ComponentUnknown
 	}
+
+	setComponentStateMetric("storageCluster", scStatus.State)
+	setComponentStateMetric("prometheus", promStatus.State)
+	setComponentStateMetric("alertmanager", amStatus.State)
+
+	// Summarize the component states into the Ready and Progressing conditions
+	notReady := []string{}
+	pending := []string{}
+	for _, component := range []struct {
+		name  string
+		state v1.ComponentState
+	}{
+		{"StorageCluster", scStatus.State},
+		{"Prometheus", promStatus.State},
+		{"Alertmanager", amStatus.State},
+	} {
+		if component.state != v1.ComponentReady {
+			notReady = append(notReady, fmt.Sprintf("%s is %s", component.name, component.state))
+		}
+		if component.state == v1.ComponentPending {
+			pending = append(pending, component.name)
+		}
+	}
+	if len(notReady) == 0 {
+		r.setCondition(v1.ConditionReady, metav1.ConditionTrue, "ComponentsReady", "All components are ready")
+	} else {
+		r.setCondition(v1.ConditionReady, metav1.ConditionFalse, "ComponentsNotReady", strings.Join(notReady, ", "))
+	}
+	if len(pending) == 0 {
+		r.setCondition(v1.ConditionProgressing, metav1.ConditionFalse, "ComponentsSettled", "No component is pending")
+	} else {
+		r.setCondition(v1.ConditionProgressing, metav1.ConditionTrue, "ComponentsPending", fmt.Sprintf("Waiting for %s", strings.Join(pending, ", ")))
+	}
+}
+
+func (r *ManagedOCSReconciler) setCondition(conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&r.managedOCS.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: r.managedOCS.Generation,
+	})
 }
 
 func (r *ManagedOCSReconciler) verifyComponentsDoNotExist() bool {
@@ -602,22 +890,16 @@ func (r *ManagedOCSReconciler) verifyComponentsDoNotExist() bool {
 func (r *ManagedOCSReconciler) reconcileStorageCluster() error {
 	r.Log.Info("Reconciling StorageCluster")
 
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.storageCluster, func() error {
+	_, err := r.createOrUpdate(r.storageCluster, r.reconcileStrategies.StorageCluster, func() error {
 		if err := r.own(r.storageCluster); err != nil {
 			return err
 		}
 
-		// Handle only strict mode reconciliation
-		if r.reconcileStrategy == v1.ReconcileStrategyStrict {
-			var desired *ocsv1.StorageCluster = nil
-			switch strings.ToLower(r.DeploymentType) {
-			case "converged":
-				var err error
-				if desired, err = r.getDesiredConvergedStorageCluster(); err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("Invalid deployment type value: %v", r.DeploymentType)
+		// The desired spec is not needed when the storage cluster is left untouched
+		if r.reconcileStrategies.StorageCluster != v1.ReconcileStrategyNone {
+			desired, err := r.deploymentProfile.getDesiredStorageCluster(r)
+			if err != nil {
+				return err
 			}
 			// Override storage cluster spec with desired spec from the template.
 			// We do not replace meta or status on purpose
@@ -633,31 +915,34 @@ func (r *ManagedOCSReconciler) reconcileStorageCluster() error {
 }
 
 func (r *ManagedOCSReconciler) getDesiredConvergedStorageCluster() (*ocsv1.StorageCluster, error) {
-	addonParams := r.addonParamSecret.Data
-
-	sizeAsString := string(addonParams[storageClassSizeKey])
-	enableMCGRaw, exists := addonParams[enableMCGKey]
-	// Setting hardcoded value here to force no MCG deployment
-	enableMCGAsString := "false"
-	if exists {
-		enableMCGAsString = string(enableMCGRaw)
-	}
-	r.Log.Info("Requested add-on settings", storageClassSizeKey, sizeAsString, enableMCGKey, enableMCGAsString)
-	desiredDeviceSetCount, err := strconv.Atoi(sizeAsString)
+	err := utils.FilterAddonParamErrors(
+		r.addonParamsErrs,
+		utils.StorageClusterSizeKey,
+		utils.EnableMCGKey,
+		utils.StorageClassNameKey,
+		utils.OSDDeviceSizeKey,
+		utils.PortableKey,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("Invalid storage cluster size value: %v", sizeAsString)
+		return nil, fmt.Errorf("Invalid storage cluster add-on parameters: %v", err)
 	}
+	desiredDeviceSetCount := r.addonParams.StorageClusterSize
+	r.Log.Info("Requested add-on settings", utils.StorageClusterSizeKey, desiredDeviceSetCount, utils.EnableMCGKey, r.addonParams.EnableMCG)
 
-	// Get the storage device set count of the current storage cluster
+	// Get the storage device set of the current storage cluster
 	currDeviceSetCount := 0
+	var currDeviceSet *ocsv1.StorageDeviceSet = nil
 	for index := range r.storageCluster.Spec.StorageDeviceSets {
 		item := &r.storageCluster.Spec.StorageDeviceSets[index]
 		if item.Name == deviceSetName {
 			currDeviceSetCount = item.Count
+			currDeviceSet = item
 			break
 		}
 	}
 
+	storageDeviceSetCount.WithLabelValues("desired").Set(float64(desiredDeviceSetCount))
+
 	sc := templates.StorageClusterTemplate.DeepCopy()
 
 	var ds *ocsv1.StorageDeviceSet = nil
@@ -672,21 +957,28 @@ func (r *ManagedOCSReconciler) getDesiredConvergedStorageCluster() (*ocsv1.Stora
 		return nil, fmt.Errorf("could not find default device set on stroage cluster")
 	}
 
-	// Prevent downscaling by comparing count from secret and count from storage cluster
+	if err := r.setDeviceSetStorageParams(sc, ds, currDeviceSet); err != nil {
+		return nil, err
+	}
+
+	// Downscaling is done one device set at a time, and only when it is safe to do so.
+	// The count is held while the OSDs of a removed device set are being purged.
 	r.Log.Info("Setting storage device set count", "Current", currDeviceSetCount, "New", desiredDeviceSetCount)
-	if currDeviceSetCount <= desiredDeviceSetCount {
-		ds.Count = desiredDeviceSetCount
+	if currDeviceSet != nil && (currDeviceSetCount > desiredDeviceSetCount || r.isOSDRemovalInProgress()) {
+		ds.Count = r.getDownscaleStepCount(currDeviceSetCount, desiredDeviceSetCount, currDeviceSet.Replica)
 	} else {
-		r.Log.V(-1).Info("Requested storage device set count will result in downscaling, which is not supported. Skipping")
-		ds.Count = currDeviceSetCount
+		if currDeviceSetCount > 0 && currDeviceSetCount < desiredDeviceSetCount {
+			r.recordEvent(eventReasonStorageExpanded, "Increasing the storage device set count from %d to %d", currDeviceSetCount, desiredDeviceSetCount)
+		}
+		ds.Count = desiredDeviceSetCount
+		r.updateDownscaleStatus(currDeviceSetCount, desiredDeviceSetCount)
 	}
 	// Check and enable MCG in Storage Cluster spec
-	mcgEnable, err := strconv.ParseBool(enableMCGAsString)
-	if err != nil {
-		return nil, fmt.Errorf("Invalid Enable MCG value: %v", enableMCGAsString)
-	}
-	if mcgEnable {
+	if r.addonParams.EnableMCG {
 		r.Log.Info("Enabling Multi Cloud Gateway")
+		if mcg := r.storageCluster.Spec.MultiCloudGateway; mcg == nil || mcg.ReconcileStrategy != "manage" {
+			r.recordEvent(eventReasonMCGEnabled, "Enabling Multi Cloud Gateway")
+		}
 		sc.Spec.MultiCloudGateway.ReconcileStrategy = "manage"
 	} else if sc.Spec.MultiCloudGateway.ReconcileStrategy == "manage" {
 		r.Log.V(-1).Info("Trying to disable Multi Cloud Gateway, Invalid operation")
@@ -695,8 +987,88 @@ func (r *ManagedOCSReconciler) getDesiredConvergedStorageCluster() (*ocsv1.Stora
 	return sc, nil
 }
 
+// getDeviceSetCount returns the count of the default device set of the given storage cluster
+func getDeviceSetCount(sc *ocsv1.StorageCluster) int {
+	for index := range sc.Spec.StorageDeviceSets {
+		if sc.Spec.StorageDeviceSets[index].Name == deviceSetName {
+			return sc.Spec.StorageDeviceSets[index].Count
+		}
+	}
+	return 0
+}
+
+// setDeviceSetStorageParams applies the storage class, OSD device size and portability
+// add-on parameters to the desired storage cluster. The storage class and the device size
+// of existing OSDs cannot be changed, once the device set is created these are kept as is.
+func (r *ManagedOCSReconciler) setDeviceSetStorageParams(sc *ocsv1.StorageCluster, ds *ocsv1.StorageDeviceSet, currDeviceSet *ocsv1.StorageDeviceSet) error {
+	storageClassName := r.addonParams.StorageClassName
+	deviceSize := r.addonParams.OSDDeviceSize.DeepCopy()
+	portable := r.addonParams.Portable
+	r.Log.Info("Requested storage settings", utils.StorageClassNameKey, storageClassName, utils.OSDDeviceSizeKey, deviceSize.String(), utils.PortableKey, portable)
+
+	var currStorageClassName *string
+	if currDeviceSet != nil {
+		currStorageClassName = currDeviceSet.DataPVCTemplate.Spec.StorageClassName
+	}
+	if currStorageClassName != nil && *currStorageClassName != "" {
+		if storageClassName != "" && storageClassName != *currStorageClassName {
+			r.Log.V(-1).Info("Changing the storage class of an existing device set is not supported. Skipping", "Current", *currStorageClassName)
+		}
+		storageClassName = *currStorageClassName
+	} else if storageClassName == "" {
+		// Only a new device set needs the default storage class of the cloud provider
+		var err error
+		if storageClassName, err = r.getDefaultStorageClassName(); err != nil {
+			return err
+		}
+	}
+
+	if currDeviceSet != nil {
+		currDeviceSize, exists := currDeviceSet.DataPVCTemplate.Spec.Resources.Requests[corev1.ResourceStorage]
+		if exists && currDeviceSize.Cmp(deviceSize) != 0 {
+			r.Log.V(-1).Info("Changing the OSD device size of an existing device set is not supported. Skipping", "Current", currDeviceSize.String())
+			deviceSize = currDeviceSize
+		}
+	}
+
+	sc.Spec.MonPVCTemplate.Spec.StorageClassName = &storageClassName
+	ds.DataPVCTemplate.Spec.StorageClassName = &storageClassName
+	ds.DataPVCTemplate.Spec.Resources.Requests[corev1.ResourceStorage] = deviceSize
+	ds.Portable = portable
+
+	return nil
+}
+
+// reconcileAlertRoutingPolicy ensures the alert routing policy ConfigMap exists, and merges
+// the policy it holds over the built-in routing of the deployment. The ConfigMap is owned by
+// the deployer but its content is managed by SRE. An invalid policy is reported and ignored,
+// in which case alerts keep being routed according to the built-in routing.
+func (r *ManagedOCSReconciler) reconcileAlertRoutingPolicy() error {
+	r.Log.Info("Reconciling alert routing policy ConfigMap")
+
+	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.alertRoutingPolicyConfigMap, func() error {
+		return r.own(r.alertRoutingPolicyConfigMap)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to update alert routing policy ConfigMap: %v", err)
+	}
+
+	overrides := &templates.AlertRoutingPolicy{}
+	if err := yaml.UnmarshalStrict([]byte(r.alertRoutingPolicyConfigMap.Data[alertRoutingPolicyConfigMapKey]), overrides); err != nil {
+		return fmt.Errorf("Unable to parse alert routing policy: %v", err)
+	}
+	policy := r.defaultAlertRoutingPolicy.Merge(overrides)
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("Invalid alert routing policy: %v", err)
+	}
+	r.alertRoutingPolicy = policy
+
+	return nil
+}
+
 // AlertRelabelConfigSecret will have configuration for relabeling the alerts that are firing.
-// It will add namespace label to firing alerts before they are sent to the alertmanager
+// It will add namespace label to firing alerts before they are sent to the alertmanager.
+// The namespace of the PVC the volume usage alerts refer to is kept in the pvc_namespace label.
 func (r *ManagedOCSReconciler) reconcileAlertRelabelConfigSecret() error {
 	r.Log.Info("Reconciling alertRelabelConfigSecret")
 
@@ -705,14 +1077,46 @@ func (r *ManagedOCSReconciler) reconcileAlertRelabelConfigSecret() error {
 			return err
 		}
 
-		alertRelabelConfig := []struct {
-			TargetLabel string `yaml:"target_label,omitempty"`
-			Replacement string `yaml:"replacement,omitempty"`
-		}{{
+		type relabelConfig struct {
+			SourceLabels []string `yaml:"source_labels,omitempty"`
+			Separator    string   `yaml:"separator,omitempty"`
+			Regex        string   `yaml:"regex,omitempty"`
+			TargetLabel  string   `yaml:"target_label,omitempty"`
+			Replacement  string   `yaml:"replacement,omitempty"`
+		}
+		alertRelabelConfig := []relabelConfig{{
+			SourceLabels: []string{"persistentvolumeclaim", "namespace"},
+			Separator:    ";",
+			Regex:        ".+;(.+)",
+			TargetLabel:  templates.PVCNamespaceLabelKey,
+			Replacement:  "$1",
+		}, {
 			TargetLabel: "namespace",
 			Replacement: r.namespace,
+		}, {
+			// The OSD alerts identify the node by the host label. The host is copied to
+			// the node label, when not set, so node alerts can inhibit them
+			SourceLabels: []string{"node", "host"},
+			Separator:    ";",
+			Regex:        ";(.+)",
+			TargetLabel:  "node",
+			Replacement:  "$1",
 		}}
 
+		// The cluster identity labels are sorted to keep the config stable between reconciles
+		identityLabels := r.getClusterIdentity().labels()
+		identityLabelKeys := []string{}
+		for key := range identityLabels {
+			identityLabelKeys = append(identityLabelKeys, key)
+		}
+		sort.Strings(identityLabelKeys)
+		for _, key := range identityLabelKeys {
+			alertRelabelConfig = append(alertRelabelConfig, relabelConfig{
+				TargetLabel: key,
+				Replacement: identityLabels[key],
+			})
+		}
+
 		config, err := yaml.Marshal(alertRelabelConfig)
 		if err != nil {
 			return fmt.Errorf("Unable to encode alert relabel conifg: %v", err)
@@ -735,7 +1139,7 @@ func (r *ManagedOCSReconciler) reconcileAlertRelabelConfigSecret() error {
 func (r *ManagedOCSReconciler) reconcilePrometheus() error {
 	r.Log.Info("Reconciling Prometheus")
 
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.prometheus, func() error {
+	_, err := r.createOrUpdate(r.prometheus, r.reconcileStrategies.Prometheus, func() error {
 		if err := r.own(r.prometheus); err != nil {
 			return err
 		}
@@ -775,6 +1179,9 @@ func (r *ManagedOCSReconciler) reconcileDMSPrometheusRule() error {
 				for _, rule := range group.Rules {
 					if rule.Alert == "DeadMansSnitch" {
 						rule.Labels["namespace"] = r.namespace
+						for key, value := range r.getClusterIdentity().labels() {
+							rule.Labels[key] = value
+						}
 					}
 				}
 			}
@@ -791,6 +1198,25 @@ func (r *ManagedOCSReconciler) reconcileDMSPrometheusRule() error {
 	return nil
 }
 
+func (r *ManagedOCSReconciler) reconcileDeployerPrometheusRule() error {
+	r.Log.Info("Reconciling Deployer Prometheus Rule")
+
+	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.deployerRule, func() error {
+		if err := r.own(r.deployerRule); err != nil {
+			return err
+		}
+		desired := templates.DeployerPrometheusRuleTemplate.DeepCopy()
+		r.deployerRule.Spec = desired.Spec
+		utils.AddLabel(r.deployerRule, monLabelKey, monLabelValue)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (r *ManagedOCSReconciler) reconcileOCSInitialization() error {
 	r.Log.Info("Reconciling OCSInitialization")
 
@@ -814,7 +1240,7 @@ func (r *ManagedOCSReconciler) reconcileOCSInitialization() error {
 
 func (r *ManagedOCSReconciler) reconcileAlertmanager() error {
 	r.Log.Info("Reconciling Alertmanager")
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.alertmanager, func() error {
+	_, err := r.createOrUpdate(r.alertmanager, r.reconcileStrategies.Alertmanager, func() error {
 		if err := r.own(r.alertmanager); err != nil {
 			return err
 		}
@@ -839,7 +1265,32 @@ func (r *ManagedOCSReconciler) reconcileAlertmanager() error {
 func (r *ManagedOCSReconciler) reconcileAlertmanagerConfig() error {
 	r.Log.Info("Reconciling AlertmanagerConfig secret")
 
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.alertmanagerConfig, func() error {
+	// Invalid notification emails are reported instead of failing the whole alerting setup
+	rejectedEmails := []v1.RejectedNotificationEmail{}
+	for _, err := range r.addonParamsErrs {
+		if utils.IsNotificationEmailKey(err.Field) {
+			rejectedEmails = append(rejectedEmails, v1.RejectedNotificationEmail{
+				Key:    err.Field,
+				Reason: err.ErrorBody(),
+			})
+		}
+	}
+	if len(rejectedEmails) > 0 {
+		r.Log.V(-1).Info("Some notification emails were rejected", "count", len(rejectedEmails))
+	}
+	r.managedOCS.Status.RejectedNotificationEmails = rejectedEmails
+
+	if err := utils.FilterAddonParamErrors(r.addonParamsErrs, utils.NotificationResolvedKey, utils.NotificationDigestKey); err != nil {
+		r.Log.V(-1).Info("Invalid notification add-on parameters, using the default values", "error", err.Error())
+	}
+
+	activeMaintenanceWindows := r.getActiveMaintenanceWindows()
+	if len(activeMaintenanceWindows) > 0 {
+		r.Log.Info("Silencing alerts during maintenance", "windows", len(activeMaintenanceWindows))
+	}
+	r.managedOCS.Status.ActiveMaintenanceWindows = activeMaintenanceWindows
+
+	_, err := r.createOrUpdate(r.alertmanagerConfig, r.reconcileStrategies.AlertmanagerConfig, func() error {
 		if err := r.own(r.alertmanagerConfig); err != nil {
 			return err
 		}
@@ -848,9 +1299,14 @@ func (r *ManagedOCSReconciler) reconcileAlertmanagerConfig() error {
 			return fmt.Errorf("Unable to get pagerduty secret: %v", err)
 		}
 		pagerdutySecretData := r.pagerdutySecret.Data
-		pagerdutyServiceKey := string(pagerdutySecretData["PAGERDUTY_KEY"])
-		if pagerdutyServiceKey == "" {
-			return fmt.Errorf("Pagerduty secret does not contain a PAGERDUTY_KEY entry")
+		pagerdutyKey := &corev1.SecretKeySelector{}
+		pagerdutyKey.LocalObjectReference.Name = r.PagerdutySecretName
+		if len(pagerdutySecretData[pagerdutyRoutingKeyKey]) > 0 {
+			pagerdutyKey.Key = pagerdutyRoutingKeyKey
+		} else if len(pagerdutySecretData[pagerdutyServiceKeyKey]) > 0 {
+			pagerdutyKey.Key = pagerdutyServiceKeyKey
+		} else {
+			return fmt.Errorf("Pagerduty secret does not contain a %v or a %v entry", pagerdutyServiceKeyKey, pagerdutyRoutingKeyKey)
 		}
 
 		if r.deadMansSnitchSecret.UID == "" {
@@ -863,20 +1319,7 @@ func (r *ManagedOCSReconciler) reconcileAlertmanagerConfig() error {
 			return fmt.Errorf("DeadMan's Snitch secret does not contain a SNITCH_URL entry")
 		}
 
-		alertingAddressList := []string{}
-		i := 0
-		for {
-			alertingAddress, found := r.addonParamSecret.Data[notificationEmailKeyPrefix+fmt.Sprintf("-%v", i)]
-			i++
-			if found {
-				alertingAddressAsString := string(alertingAddress)
-				if alertingAddressAsString != "" {
-					alertingAddressList = append(alertingAddressList, alertingAddressAsString)
-				}
-			} else {
-				break
-			}
-		}
+		alertingAddressList := r.addonParams.NotificationEmails
 
 		smtpSecretData := map[string][]byte{}
 		if r.smtpSecret.UID == "" {
@@ -901,37 +1344,77 @@ func (r *ManagedOCSReconciler) reconcileAlertmanagerConfig() error {
 		if smtpPassword == "" {
 			return fmt.Errorf("smtp secret does not contain a password entry")
 		}
-		smtpHTML, err := ioutil.ReadFile(r.CustomerNotificationHTMLPath)
-		if err != nil {
-			return fmt.Errorf("unable to read customernotification.html file: %v", err)
+		// Optional receivers are enabled by the presence of a valid secret, alerts are not
+		// routed to the ones that are disabled
+		enabledReceivers := r.getEnabledAlertReceivers()
+		enabledReceiverNames := []string{}
+		for _, receiver := range enabledReceivers {
+			enabledReceiverNames = append(enabledReceiverNames, receiver.receiverName)
+		}
+		disabledReceiverNames := []string{}
+		for _, receiver := range r.optionalAlertReceivers() {
+			if !utils.Contains(enabledReceiverNames, receiver.receiverName) {
+				disabledReceiverNames = append(disabledReceiverNames, receiver.receiverName)
+			}
 		}
 
-		desired := templates.AlertmanagerConfigTemplate.DeepCopy()
+		desired := templates.NewAlertmanagerConfig(r.alertRoutingPolicy.WithoutReceivers(disabledReceiverNames))
 		for i := range desired.Spec.Receivers {
 			receiver := &desired.Spec.Receivers[i]
+			for _, optionalReceiver := range r.optionalAlertReceivers() {
+				if receiver.Name != optionalReceiver.receiverName {
+					continue
+				}
+				if utils.Contains(enabledReceiverNames, receiver.Name) {
+					if err := optionalReceiver.configure(receiver, optionalReceiver.secret); err != nil {
+						return err
+					}
+				} else {
+					receiver.OpsGenieConfigs = nil
+					receiver.SlackConfigs = nil
+					receiver.WebhookConfigs = nil
+				}
+			}
 			switch receiver.Name {
 			case "pagerduty":
-				receiver.PagerDutyConfigs[0].ServiceKey.Key = "PAGERDUTY_KEY"
-				receiver.PagerDutyConfigs[0].ServiceKey.LocalObjectReference.Name = r.PagerdutySecretName
-				receiver.PagerDutyConfigs[0].Details[0].Key = "SOP"
-				receiver.PagerDutyConfigs[0].Details[0].Value = r.SOPEndpoint
+				if pagerdutyKey.Key == pagerdutyRoutingKeyKey {
+					receiver.PagerDutyConfigs[0].ServiceKey = nil
+					receiver.PagerDutyConfigs[0].RoutingKey = pagerdutyKey
+				} else {
+					receiver.PagerDutyConfigs[0].ServiceKey = pagerdutyKey
+				}
+				receiver.PagerDutyConfigs[0].Details = r.getPagerdutyDetails()
 			case "DeadMansSnitch":
 				receiver.WebhookConfigs[0].URL = &dmsURL
-			case "SendGrid":
+			case "SendGrid", "SendGridDigest":
 				if len(alertingAddressList) > 0 {
+					receiver.EmailConfigs[0].SendResolved = &r.addonParams.NotificationResolved
 					receiver.EmailConfigs[0].Smarthost = fmt.Sprintf("%s:%s", smtpHost, smtpPort)
 					receiver.EmailConfigs[0].AuthUsername = smtpUsername
 					receiver.EmailConfigs[0].AuthPassword.LocalObjectReference.Name = r.SMTPSecretName
 					receiver.EmailConfigs[0].AuthPassword.Key = "password"
 					receiver.EmailConfigs[0].From = r.AlertSMTPFrom
 					receiver.EmailConfigs[0].To = strings.Join(alertingAddressList, ", ")
-					receiver.EmailConfigs[0].HTML = string(smtpHTML)
+					receiver.EmailConfigs[0].HTML = r.CustomerNotification.HTML
+					if receiver.Name == "SendGrid" {
+						receiver.EmailConfigs[0].Headers[0].Value = r.CustomerNotification.Subject
+					} else {
+						receiver.EmailConfigs[0].Headers[0].Value = r.CustomerNotification.DigestSubject
+					}
 				} else {
 					r.Log.V(-1).Info("Customer Email for alert notification is not provided")
 					receiver.EmailConfigs = []promv1a1.EmailConfig{}
 				}
 			}
 		}
+		if r.addonParams.NotificationDigest {
+			if err := templates.EnableCustomerNotificationDigest(&desired); err != nil {
+				return err
+			}
+		}
+		if err := templates.SilenceAlerts(&desired, getMaintenanceWindowMatchers(activeMaintenanceWindows)); err != nil {
+			return err
+		}
 		r.alertmanagerConfig.Spec = desired.Spec
 		utils.AddLabel(r.alertmanagerConfig, monLabelKey, monLabelValue)
 
@@ -941,6 +1424,27 @@ func (r *ManagedOCSReconciler) reconcileAlertmanagerConfig() error {
 	return err
 }
 
+// getPagerdutyDetails returns the custom details attached to every page, which let the
+// incident tooling correlate pages with the cluster that fired them
+func (r *ManagedOCSReconciler) getPagerdutyDetails() []promv1a1.KeyValue {
+	details := []promv1a1.KeyValue{{
+		Key:   "SOP",
+		Value: r.SOPEndpoint,
+	}}
+	clusterID := string(r.pagerdutySecret.Data[pagerdutyClusterIDKey])
+	if clusterID == "" {
+		clusterID = r.getClusterIdentity().clusterID
+	}
+	if clusterID != "" {
+		details = append(details, promv1a1.KeyValue{Key: "cluster_id", Value: clusterID})
+	}
+	if r.AddonName != "" {
+		details = append(details, promv1a1.KeyValue{Key: "addon_name", Value: r.AddonName})
+	}
+	details = append(details, promv1a1.KeyValue{Key: "namespace", Value: r.namespace})
+	return details
+}
+
 func (r *ManagedOCSReconciler) reconcileK8SMetricsServiceMonitorAuthSecret() error {
 	r.Log.Info("Reconciling k8sMetricsServiceMonitorAuthSecret")
 
@@ -991,7 +1495,7 @@ func (r *ManagedOCSReconciler) reconcileK8SMetricsServiceMonitorAuthSecret() err
 func (r *ManagedOCSReconciler) reconcileK8SMetricsServiceMonitor() error {
 	r.Log.Info("Reconciling k8sMetricsServiceMonitor")
 
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.k8sMetricsServiceMonitor, func() error {
+	_, err := r.createOrUpdate(r.k8sMetricsServiceMonitor, v1.ReconcileStrategyStrict, func() error {
 		if err := r.own(r.k8sMetricsServiceMonitor); err != nil {
 			return err
 		}
@@ -1005,6 +1509,25 @@ func (r *ManagedOCSReconciler) reconcileK8SMetricsServiceMonitor() error {
 	return nil
 }
 
+// reconcileDeployerMetricsServiceMonitor exposes the deployer metrics to the managed Prometheus
+func (r *ManagedOCSReconciler) reconcileDeployerMetricsServiceMonitor() error {
+	r.Log.Info("Reconciling deployerMetricsServiceMonitor")
+
+	_, err := r.createOrUpdate(r.deployerMetricsServiceMonitor, v1.ReconcileStrategyStrict, func() error {
+		if err := r.own(r.deployerMetricsServiceMonitor); err != nil {
+			return err
+		}
+		desired := templates.DeployerMetricsServiceMonitorTemplate.DeepCopy()
+		r.deployerMetricsServiceMonitor.Spec = desired.Spec
+		utils.AddLabel(r.deployerMetricsServiceMonitor, monLabelKey, monLabelValue)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to update deployerMetricsServiceMonitor: %v", err)
+	}
+	return nil
+}
+
 // reconcileMonitoringResources labels all monitoring resources (ServiceMonitors, PodMonitors, and PrometheusRules)
 // found in the target namespace with a label that matches the label selector the defined on the Prometheus resource
 // we are reconciling in reconcilePrometheus. Doing so instructs the Prometheus instance to notice and react to these labeled
@@ -1166,7 +1689,7 @@ func (r *ManagedOCSReconciler) reconcileRookCephOperatorConfig() error {
 }
 
 func (r *ManagedOCSReconciler) reconcileEgressNetworkPolicy() error {
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.egressNetworkPolicy, func() error {
+	_, err := r.createOrUpdate(r.egressNetworkPolicy, r.reconcileStrategies.NetworkPolicies, func() error {
 		if err := r.own(r.egressNetworkPolicy); err != nil {
 			return err
 		}
@@ -1204,13 +1727,19 @@ func (r *ManagedOCSReconciler) reconcileEgressNetworkPolicy() error {
 		smtpEgressRule.To.DNSName = smtpHost
 		smtpEgressRule.Type = openshiftv1.EgressNetworkPolicyRuleAllow
 
-		desired.Spec.Egress = append(
-			[]openshiftv1.EgressNetworkPolicyRule{
-				dmsEgressRule,
-				smtpEgressRule,
-			},
-			desired.Spec.Egress...,
-		)
+		egressRules := []openshiftv1.EgressNetworkPolicyRule{
+			dmsEgressRule,
+			smtpEgressRule,
+		}
+
+		for _, host := range r.getEnabledAlertReceiverHosts() {
+			receiverEgressRule := openshiftv1.EgressNetworkPolicyRule{}
+			receiverEgressRule.To.DNSName = host
+			receiverEgressRule.Type = openshiftv1.EgressNetworkPolicyRuleAllow
+			egressRules = append(egressRules, receiverEgressRule)
+		}
+
+		desired.Spec.Egress = append(egressRules, desired.Spec.Egress...)
 		r.egressNetworkPolicy.Spec = desired.Spec
 		return nil
 	})
@@ -1221,33 +1750,46 @@ func (r *ManagedOCSReconciler) reconcileEgressNetworkPolicy() error {
 }
 
 func (r *ManagedOCSReconciler) reconcileIngressNetworkPolicy() error {
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.ingressNetworkPolicy, func() error {
-		if err := r.own(r.ingressNetworkPolicy); err != nil {
-			return err
-		}
-		desired := templates.NetworkPolicyTemplate.DeepCopy()
-		r.ingressNetworkPolicy.Spec = desired.Spec
-		return nil
-	})
-	if err != nil {
+	if err := r.reconcileNetworkPolicy(r.ingressNetworkPolicy, r.deploymentProfile.ingressNetworkPolicyTemplate); err != nil {
 		return fmt.Errorf("Failed to update ingress NetworkPolicy: %v", err)
 	}
 	return nil
 }
 
 func (r *ManagedOCSReconciler) reconcileCephIngressNetworkPolicy() error {
-	_, err := ctrl.CreateOrUpdate(r.ctx, r.Client, r.cephIngressNetworkPolicy, func() error {
-		if err := r.own(r.cephIngressNetworkPolicy); err != nil {
+	if err := r.reconcileNetworkPolicy(r.cephIngressNetworkPolicy, r.deploymentProfile.cephIngressNetworkPolicyTemplate); err != nil {
+		return fmt.Errorf("Failed to update ceph ingress NetworkPolicy: %v", err)
+	}
+	return nil
+}
+
+func (r *ManagedOCSReconciler) reconcileProviderIngressNetworkPolicy() error {
+	desired, err := r.getDesiredProviderNetworkPolicy()
+	if err != nil {
+		return err
+	}
+	if err := r.reconcileNetworkPolicy(r.providerIngressNetworkPolicy, desired); err != nil {
+		return fmt.Errorf("Failed to update provider ingress NetworkPolicy: %v", err)
+	}
+	return nil
+}
+
+// reconcileNetworkPolicy enforces the spec of a network policy from a template.
+// A nil template indicates that the deployment profile does not need the
+// policy, in which case it is removed
+func (r *ManagedOCSReconciler) reconcileNetworkPolicy(networkPolicy *netv1.NetworkPolicy, template *netv1.NetworkPolicy) error {
+	if template == nil {
+		return r.delete(networkPolicy)
+	}
+	_, err := r.createOrUpdate(networkPolicy, r.reconcileStrategies.NetworkPolicies, func() error {
+		if err := r.own(networkPolicy); err != nil {
 			return err
 		}
-		desired := templates.CephNetworkPolicyTemplate.DeepCopy()
-		r.cephIngressNetworkPolicy.Spec = desired.Spec
+		desired := template.DeepCopy()
+		networkPolicy.Spec = desired.Spec
 		return nil
 	})
-	if err != nil {
-		return fmt.Errorf("Failed to update ceph ingress NetworkPolicy: %v", err)
-	}
-	return nil
+	return err
 }
 
 func (r *ManagedOCSReconciler) checkUninstallCondition() bool {
@@ -1273,42 +1815,14 @@ func (r *ManagedOCSReconciler) areComponentsReadyForUninstall() bool {
 		subComponents.Alertmanager.State == v1.ComponentReady
 }
 
-func (r *ManagedOCSReconciler) findOCSVolumeClaims() (bool, error) {
-	// get all the storage class
-	storageClassList := storagev1.StorageClassList{}
-	if err := r.UnrestrictedClient.List(r.ctx, &storageClassList); err != nil {
-		return false, fmt.Errorf("unable to list storage classes: %v", err)
-	}
-
-	// create a set of storage class names who are using the OCS provisioner
-	// provisioner name prefixed with the namespace name
-	ocsStorageClass := make(map[string]bool)
-	for i := range storageClassList.Items {
-		storageClass := &storageClassList.Items[i]
-		if strings.HasPrefix(storageClassList.Items[i].Provisioner, r.namespace) {
-			ocsStorageClass[storageClass.Name] = true
-		}
-	}
-
-	// get all the PVCs
-	pvcList := &corev1.PersistentVolumeClaimList{}
-	if err := r.UnrestrictedClient.List(r.ctx, pvcList); err != nil {
-		return false, fmt.Errorf("unable to list pvcs: %v", err)
-	}
-
-	// check if there are any PVCs using OCS storage classes
-	for i := range pvcList.Items {
-		scName := *pvcList.Items[i].Spec.StorageClassName
-		if ocsStorageClass[scName] {
-			return true, nil
-		}
-	}
-	return false, nil
-}
-
 func (r *ManagedOCSReconciler) reconcileCSV() error {
 	r.Log.Info("Reconciling CSVs")
 
+	if r.reconcileStrategies.CSVResources == v1.ReconcileStrategyNone {
+		r.Log.Info("Skipping CSV reconciliation, reconcile strategy is none")
+		return nil
+	}
+
 	csvList := opv1a1.ClusterServiceVersionList{}
 	if err := r.list(&csvList); err != nil {
 		return fmt.Errorf("unable to list csv resources: %v", err)
@@ -1336,20 +1850,11 @@ func (r *ManagedOCSReconciler) updateOCSCSV(csv *opv1a1.ClusterServiceVersion) e
 		containers := deployments[i].Spec.Template.Spec.Containers
 		for j := range containers {
 			switch container := &containers[j]; container.Name {
-			case "ocs-operator":
-				resources := utils.GetResourceRequirements("ocs-operator")
-				if !equality.Semantic.DeepEqual(container.Resources, resources) {
-					container.Resources = resources
-					isChanged = true
-				}
-			case "rook-ceph-operator":
-				resources := utils.GetResourceRequirements("rook-ceph-operator")
-				if !equality.Semantic.DeepEqual(container.Resources, resources) {
-					container.Resources = resources
-					isChanged = true
+			case "ocs-operator", "rook-ceph-operator", "ocs-metrics-exporter":
+				resources, err := r.getDesiredCSVContainerResources(container)
+				if err != nil {
+					return err
 				}
-			case "ocs-metrics-exporter":
-				resources := utils.GetResourceRequirements("ocs-metrics-exporter")
 				if !equality.Semantic.DeepEqual(container.Resources, resources) {
 					container.Resources = resources
 					isChanged = true
@@ -1367,6 +1872,30 @@ func (r *ManagedOCSReconciler) updateOCSCSV(csv *opv1a1.ClusterServiceVersion) e
 	return nil
 }
 
+// getDesiredCSVContainerResources returns the resource requirements of a CSV container. In
+// merge mode, the resources that the deployer does not set are kept from the live container.
+func (r *ManagedOCSReconciler) getDesiredCSVContainerResources(container *corev1.Container) (corev1.ResourceRequirements, error) {
+	desired := utils.GetResourceRequirements(container.Name)
+	if r.reconcileStrategies.CSVResources != v1.ReconcileStrategyMerge {
+		return desired, nil
+	}
+
+	liveFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&container.Resources)
+	if err != nil {
+		return desired, err
+	}
+	desiredFields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&desired)
+	if err != nil {
+		return desired, err
+	}
+	merged := corev1.ResourceRequirements{}
+	mergedFields, _ := mergeFields(liveFields, desiredFields).(map[string]interface{})
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(mergedFields, &merged); err != nil {
+		return desired, err
+	}
+	return merged, nil
+}
+
 func (r *ManagedOCSReconciler) updateMCGCSV(csv *opv1a1.ClusterServiceVersion) error {
 	isChanged := false
 	mcgDeployments := csv.Spec.InstallStrategy.StrategySpec.DeploymentSpecs
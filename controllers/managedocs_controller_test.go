@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -13,16 +15,19 @@ import (
 	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	ocsv1 "github.com/red-hat-storage/ocs-operator/api/v1"
 	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
 	utils "github.com/red-hat-storage/ocs-osd-deployer/testutils"
 	ctrlutils "github.com/red-hat-storage/ocs-osd-deployer/utils"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 var _ = Describe("ManagedOCS controller", func() {
@@ -153,6 +158,12 @@ var _ = Describe("ManagedOCS controller", func() {
 			Namespace: testPrimaryNamespace,
 		},
 	}
+	alertRoutingPolicyConfigMapTemplate := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      alertRoutingPolicyConfigMapName,
+			Namespace: testPrimaryNamespace,
+		},
+	}
 	addonConfigMapTemplate := corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      testAddonConfigMapName,
@@ -186,6 +197,12 @@ var _ = Describe("ManagedOCS controller", func() {
 			Namespace: testPrimaryNamespace,
 		},
 	}
+	providerIngressNetworkPolicyTemplate := netv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      providerIngressNetworkPolicyName,
+			Namespace: testPrimaryNamespace,
+		},
+	}
 	pvc1StorageClassName := storageClassRbdName
 	pvc1Template := corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
@@ -222,6 +239,40 @@ var _ = Describe("ManagedOCS controller", func() {
 			},
 		},
 	}
+	retainedPVTemplate := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-retained-pv",
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			StorageClassName:              storageClassRbdName,
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Capacity: corev1.ResourceList{
+				corev1.ResourceStorage: resource.MustParse("1Gi"),
+			},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/tmp/test-retained-pv"},
+			},
+		},
+	}
+	classlessPVCTemplate := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-classless-pvc",
+			Namespace: testPrimaryNamespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}
 	csvTemplate := opv1a1.ClusterServiceVersion{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      testDeployerCSVName,
@@ -270,6 +321,12 @@ var _ = Describe("ManagedOCS controller", func() {
 			Namespace: testPrimaryNamespace,
 		},
 	}
+	deployerMetricsServiceMonitorTemplate := promv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      deployerMetricsServiceMonitorName,
+			Namespace: testPrimaryNamespace,
+		},
+	}
 	alertRelabelConfigSecretTemplate := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      alertRelabelConfigSecretName,
@@ -491,41 +548,56 @@ var _ = Describe("ManagedOCS controller", func() {
 				}
 				utils.EnsureNoResources(k8sClient, ctx, resList, timeout, interval)
 			})
+			It("should record the addition of the finalizer", func() {
+				utils.WaitForEvent(k8sClient, ctx, managedOCSTemplate.DeepCopy(), "FinalizerAdded", timeout, interval)
+			})
 		})
 		When("there is no size field in the add-on parameters secret", func() {
-			It("should not create a reconciled resources", func() {
+			It("should not create a storagecluster but still reconcile independent resources", func() {
 				// Create empty add-on parameters secret
 				secret := addonParamsSecretTemplate.DeepCopy()
 				Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
 
-				// Ensure, over a period of time, that the resources are not created
-				resList := []client.Object{
-					scTemplate.DeepCopy(),
-					promTemplate.DeepCopy(),
-					amTemplate.DeepCopy(),
-				}
-				utils.EnsureNoResources(k8sClient, ctx, resList, timeout, interval)
+				// Ensure, over a period of time, that the storagecluster is not created
+				utils.EnsureNoResource(k8sClient, ctx, scTemplate.DeepCopy(), timeout, interval)
+
+				// Independent phases are not blocked by the storagecluster failure
+				utils.WaitForResource(k8sClient, ctx, promTemplate.DeepCopy(), timeout, interval)
+				utils.WaitForResource(k8sClient, ctx, amTemplate.DeepCopy(), timeout, interval)
 
 				// Remove the secret for future cases
 				Expect(k8sClient.Delete(ctx, secret)).Should(Succeed())
 			})
 		})
 		When("there is an invalid size value in the add-on parameters secret", func() {
-			It("should not create reconciled resources", func() {
+			It("should not create a storagecluster", func() {
 				// Create a invalid add-on parameters secret
 				secret := addonParamsSecretTemplate.DeepCopy()
 				secret.Data["size"] = []byte("AA")
 				Expect(k8sClient.Create(ctx, secret)).Should(Succeed())
 
-				// Ensure, over a period of time, that the resources are not created
-				resList := []client.Object{
-					scTemplate.DeepCopy(),
-					promTemplate.DeepCopy(),
-					amTemplate.DeepCopy(),
-				}
-				utils.EnsureNoResources(k8sClient, ctx, resList, timeout, interval)
+				// Ensure, over a period of time, that the storagecluster is not created
+				utils.EnsureNoResource(k8sClient, ctx, scTemplate.DeepCopy(), timeout, interval)
+			})
+			It("should report the failed phase in the ManagedOCS status", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() v1.ReconcilePhaseStatus {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					for _, phase := range managedOCS.Status.ReconcilePhases {
+						if phase.Name == "StorageCluster" {
+							return phase
+						}
+					}
+					return v1.ReconcilePhaseStatus{}
+				}, timeout, interval).Should(And(
+					WithTransform(func(p v1.ReconcilePhaseStatus) v1.ReconcilePhaseState { return p.State }, Equal(v1.ReconcilePhaseFailed)),
+					WithTransform(func(p v1.ReconcilePhaseStatus) string { return p.Message }, ContainSubstring("size: Invalid value")),
+				))
+				Expect(meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionDegraded)).Should(BeTrue())
 
 				// Remove the secret for future cases
+				secret := addonParamsSecretTemplate.DeepCopy()
 				Expect(k8sClient.Delete(ctx, secret)).Should(Succeed())
 			})
 		})
@@ -584,6 +656,9 @@ var _ = Describe("ManagedOCS controller", func() {
 					return sc.Spec.MultiCloudGateway.ReconcileStrategy == "manage"
 				}, timeout, interval).Should(BeTrue())
 			})
+			It("should record that MCG was enabled", func() {
+				utils.WaitForEvent(k8sClient, ctx, managedOCSTemplate.DeepCopy(), "MCGEnabled", timeout, interval)
+			})
 		})
 		When("MCG is already enabled and enable-mcg value in addon-on parameter secret is false", func() {
 			It("should not change storagecluster's MCG reconcile strategy to 'ignore' or 'standalone'", func() {
@@ -628,6 +703,25 @@ var _ = Describe("ManagedOCS controller", func() {
 
 				By("Creating an alertmanager resource")
 				utils.WaitForResource(k8sClient, ctx, amTemplate.DeepCopy(), timeout, interval)
+
+				By("Creating a service monitor for the deployer metrics")
+				utils.WaitForResource(k8sClient, ctx, deployerMetricsServiceMonitorTemplate.DeepCopy(), timeout, interval)
+			})
+			It("should expose the reconcile health metrics", func() {
+				Eventually(func() []string {
+					families, err := metrics.Registry.Gather()
+					Expect(err).ToNot(HaveOccurred())
+					names := []string{}
+					for _, family := range families {
+						names = append(names, family.GetName())
+					}
+					return names
+				}, timeout, interval).Should(ContainElements(
+					"managedocs_component_state",
+					"managedocs_reconcile_phase_duration_seconds",
+					"managedocs_storage_device_set_count",
+					"managedocs_uninstall_blocking_resources",
+				))
 			})
 		})
 		When("size is increased in the add-on parameters secret", func() {
@@ -657,6 +751,31 @@ var _ = Describe("ManagedOCS controller", func() {
 				}, timeout, interval).Should(BeTrue())
 
 			})
+			It("should record the storage expansion", func() {
+				utils.WaitForEvent(k8sClient, ctx, managedOCSTemplate.DeepCopy(), "StorageExpanded", timeout, interval)
+			})
+			It("should not report its own storage cluster update as drift", func() {
+				utils.EnsureNoEvent(k8sClient, ctx, scTemplate.DeepCopy(), "DriftCorrected", timeout, interval)
+			})
+			It("should report the live storage device set count", func() {
+				Eventually(func() float64 {
+					families, err := metrics.Registry.Gather()
+					Expect(err).ToNot(HaveOccurred())
+					for _, family := range families {
+						if family.GetName() != "managedocs_storage_device_set_count" {
+							continue
+						}
+						for _, metric := range family.GetMetric() {
+							for _, label := range metric.GetLabel() {
+								if label.GetName() == "type" && label.GetValue() == "actual" {
+									return metric.GetGauge().GetValue()
+								}
+							}
+						}
+					}
+					return -1
+				}, timeout, interval).Should(Equal(4.0))
+			})
 		})
 		When("size is decreased in the add-on parameters secret", func() {
 			It("should not decrease storagecluster's storage device set count", func() {
@@ -683,11 +802,74 @@ var _ = Describe("ManagedOCS controller", func() {
 					return ds != nil && ds.Count == 4
 				}, timeout, interval).Should(BeTrue())
 
+				// Ceph capacity cannot be verified, the refusal should be reported in the status
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() *v1.DownscaleStatus {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return managedOCS.Status.Downscale
+				}, timeout, interval).Should(And(
+					Not(BeNil()),
+					WithTransform(func(s *v1.DownscaleStatus) v1.DownscaleState { return s.State }, Equal(v1.DownscaleRefused)),
+					WithTransform(func(s *v1.DownscaleStatus) int { return s.RequestedCount }, Equal(1)),
+				))
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "DownscaleRefused", timeout, interval)
+
 				// Revert the size in add-on param secret
 				secret.Data["size"] = []byte("4")
 				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
 			})
 		})
+		When("there is an invalid osd-device-size value in the add-on parameters secret", func() {
+			It("should report the failed phase in the ManagedOCS status", func() {
+				secret := addonParamsSecretTemplate.DeepCopy()
+				secret.Data["size"] = []byte("4")
+				secret.Data["enable-mcg"] = []byte("false")
+				secret.Data["osd-device-size"] = []byte("-1Ti")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					for _, phase := range managedOCS.Status.ReconcilePhases {
+						if phase.Name == "StorageCluster" {
+							return phase.Message
+						}
+					}
+					return ""
+				}, timeout, interval).Should(ContainSubstring("osd-device-size: Invalid value"))
+
+				// Remove the invalid value from the add-on param secret
+				delete(secret.Data, "osd-device-size")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+			})
+		})
+		When("the storage class is changed in the add-on parameters secret", func() {
+			It("should not change the storage class of the existing storage device set", func() {
+				secret := addonParamsSecretTemplate.DeepCopy()
+				secret.Data["size"] = []byte("4")
+				secret.Data["enable-mcg"] = []byte("false")
+				secret.Data["storage-class"] = []byte("test-storage-class")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+
+				Consistently(func() string {
+					sc := scTemplate.DeepCopy()
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(sc), sc)).Should(Succeed())
+					for index := range sc.Spec.StorageDeviceSets {
+						item := &sc.Spec.StorageDeviceSets[index]
+						if item.Name == deviceSetName && item.DataPVCTemplate.Spec.StorageClassName != nil {
+							return *item.DataPVCTemplate.Spec.StorageClassName
+						}
+					}
+					return ""
+				}, timeout, interval).Should(Equal("gp2"))
+
+				// Remove the storage class from the add-on param secret
+				delete(secret.Data, "storage-class")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+			})
+		})
 		When("there is a rook-ceph-operator-config ConfigMap", func() {
 			It("should ensure there are RBD CSI resource limits", func() {
 				configMap := rookConfigMapTemplate.DeepCopy()
@@ -712,7 +894,7 @@ var _ = Describe("ManagedOCS controller", func() {
 			})
 		})
 		When("there is no rook-ceph-operator-config ConfigMap", func() {
-			It("should not create reconciled resources", func() {
+			It("should not create the storagecluster", func() {
 				resList := []client.Object{
 					scTemplate.DeepCopy(),
 					promTemplate.DeepCopy(),
@@ -721,8 +903,8 @@ var _ = Describe("ManagedOCS controller", func() {
 
 				// Delete the configMap which will throw an error that
 				// the rook-ceph-operator-config configMap not found
-				// This will block creation of resources that gets created
-				// after reconcile of rook-ceph-operator-config configMap
+				// This will block the creation of the storagecluster which
+				// depends on the rook-ceph-operator-config configMap
 				configMap := rookConfigMapTemplate.DeepCopy()
 				Expect(k8sClient.Delete(ctx, configMap)).Should(Succeed())
 
@@ -732,9 +914,13 @@ var _ = Describe("ManagedOCS controller", func() {
 					Expect(k8sClient.Delete(ctx, object)).Should(Succeed())
 				}
 
-				// Resource shouldn't get created as the rook-ceph-operator-config configMap
-				// was deleted
-				utils.EnsureNoResources(k8sClient, ctx, resList, timeout, interval)
+				// The storagecluster shouldn't get created as the rook-ceph-operator-config
+				// configMap was deleted
+				utils.EnsureNoResource(k8sClient, ctx, scTemplate.DeepCopy(), timeout, interval)
+
+				// Resources that do not depend on the configMap are still reconciled
+				utils.WaitForResource(k8sClient, ctx, promTemplate.DeepCopy(), timeout, interval)
+				utils.WaitForResource(k8sClient, ctx, amTemplate.DeepCopy(), timeout, interval)
 
 				// Recreate the configMap for other tests.
 				Expect(k8sClient.Create(ctx, configMap)).Should(Succeed())
@@ -793,6 +979,23 @@ var _ = Describe("ManagedOCS controller", func() {
 				// Wait for the alertRelabelConfigSecret to be recreated
 				utils.WaitForResource(k8sClient, ctx, alertRelabelConfigSecretTemplate.DeepCopy(), timeout, interval)
 			})
+			It("should inject the cluster identity labels into the alerts", func() {
+				secret := alertRelabelConfigSecretTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(secret), secret)).Should(Succeed())
+				config := string(secret.Data[alertRelabelConfigSecretKey])
+				Expect(config).Should(ContainSubstring("target_label: cluster_id"))
+				Expect(config).Should(ContainSubstring("replacement: " + testClusterID))
+				Expect(config).Should(ContainSubstring("target_label: cluster_name"))
+				Expect(config).Should(ContainSubstring("replacement: " + testAddonName))
+			})
+			It("should keep the namespace of the PVCs in a separate label", func() {
+				secret := alertRelabelConfigSecretTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(secret), secret)).Should(Succeed())
+				config := string(secret.Data[alertRelabelConfigSecretKey])
+				Expect(config).Should(ContainSubstring("target_label: " + templates.PVCNamespaceLabelKey))
+				Expect(strings.Index(config, "target_label: "+templates.PVCNamespaceLabelKey)).
+					Should(BeNumerically("<", strings.Index(config, "target_label: namespace")))
+			})
 		})
 		When("prometheus has non-ready replicas", func() {
 			It("should reflect that in the ManagedOCS resource status", func() {
@@ -879,6 +1082,15 @@ var _ = Describe("ManagedOCS controller", func() {
 					return managedOCS.Status.Components.Alertmanager.State
 				}, timeout, interval).Should(Equal(v1.ComponentReady))
 			})
+			It("should set the Ready condition when all components are ready", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionReady)
+				}, timeout, interval).Should(BeTrue())
+				Expect(meta.IsStatusConditionFalse(managedOCS.Status.Conditions, v1.ConditionProgressing)).Should(BeTrue())
+			})
 		})
 		When("the storagecluster resource is deleted", func() {
 			It("should create a new storagecluster in the namespace", func() {
@@ -985,6 +1197,10 @@ var _ = Describe("ManagedOCS controller", func() {
 					return &prom.Spec
 				}, timeout, interval).Should(Equal(spec))
 			})
+			It("should report the reverted changes as an event", func() {
+				// The event lists the paths of the reverted fields
+				utils.WaitForEventWithMessage(k8sClient, ctx, promTemplate.DeepCopy(), "DriftCorrected", "spec.", timeout, interval)
+			})
 		})
 		When("the prometheus resource is deleted", func() {
 			It("should create a new prometheus in the namespace", func() {
@@ -995,6 +1211,46 @@ var _ = Describe("ManagedOCS controller", func() {
 				utils.WaitForResource(k8sClient, ctx, promTemplate.DeepCopy(), timeout, interval)
 			})
 		})
+		When("the prometheus resource is modified while its reconcile strategy is set to merge", func() {
+			It("should keep the added fields and revert the operator controlled ones", func() {
+				// Set the prometheus reconcile strategy to merge
+				managedOCS := managedOCSTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+				managedOCS.Spec.ComponentReconcileStrategies.Prometheus = v1.ReconcileStrategyMerge
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+
+				Eventually(func() v1.ReconcileStrategy {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+					return managedOCS.Status.ComponentReconcileStrategies.Prometheus
+				}, timeout, interval).Should(Equal(v1.ReconcileStrategyMerge))
+
+				// Add a field and remove an operator controlled one
+				prom := promTemplate.DeepCopy()
+				promKey := utils.GetResourceKey(prom)
+				Expect(k8sClient.Get(ctx, promKey, prom)).Should(Succeed())
+				prom.Spec.ExternalURL = "https://prometheus.example.com"
+				prom.Spec.AdditionalAlertRelabelConfigs = nil
+				Expect(k8sClient.Update(ctx, prom)).Should(Succeed())
+
+				Eventually(func() bool {
+					prom := promTemplate.DeepCopy()
+					Expect(k8sClient.Get(ctx, promKey, prom)).Should(Succeed())
+					return prom.Spec.AdditionalAlertRelabelConfigs != nil &&
+						prom.Spec.ExternalURL == "https://prometheus.example.com"
+				}, timeout, interval).Should(BeTrue())
+
+				// Restore the strict reconcile strategy
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+				managedOCS.Spec.ComponentReconcileStrategies.Prometheus = ""
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+
+				Eventually(func() string {
+					prom := promTemplate.DeepCopy()
+					Expect(k8sClient.Get(ctx, promKey, prom)).Should(Succeed())
+					return prom.Spec.ExternalURL
+				}, timeout, interval).Should(BeEmpty())
+			})
+		})
 		When("the alertmanager resource is modified", func() {
 			It("should revert the changes and bring the resource back to its managed state", func() {
 				// Get an updated alertmanager
@@ -1031,6 +1287,20 @@ var _ = Describe("ManagedOCS controller", func() {
 				// Ensure, over a period of time, that the resources are not created
 				utils.EnsureNoResource(k8sClient, ctx, amConfigTemplate.DeepCopy(), timeout, interval)
 			})
+			It("should report the failure in the ManagedOCS conditions", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() *metav1.Condition {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return meta.FindStatusCondition(managedOCS.Status.Conditions, v1.ConditionAlertingConfigured)
+				}, timeout, interval).Should(And(
+					Not(BeNil()),
+					WithTransform(func(c *metav1.Condition) metav1.ConditionStatus { return c.Status }, Equal(metav1.ConditionFalse)),
+					WithTransform(func(c *metav1.Condition) string { return c.Reason }, Equal("AlertmanagerConfigReconcileFailed")),
+					WithTransform(func(c *metav1.Condition) string { return c.Message }, ContainSubstring("pagerduty secret")),
+				))
+				Expect(meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionDegraded)).Should(BeTrue())
+			})
 		})
 		When("there is no value for PAGERDUTY_KEY in the pagerduty secret", func() {
 			It("should not create alertmanager config", func() {
@@ -1064,6 +1334,13 @@ var _ = Describe("ManagedOCS controller", func() {
 				utils.EnsureNoResource(k8sClient, ctx, amConfigTemplate.DeepCopy(), timeout, interval)
 
 			})
+			It("should still reconcile the ingress NetworkPolicies", func() {
+				Expect(k8sClient.Delete(ctx, ingressNetworkPolicyTemplate.DeepCopy())).Should(Succeed())
+				Expect(k8sClient.Delete(ctx, cephIngressNetworkPolicyTemplate.DeepCopy())).Should(Succeed())
+
+				utils.WaitForResource(k8sClient, ctx, ingressNetworkPolicyTemplate.DeepCopy(), timeout, interval)
+				utils.WaitForResource(k8sClient, ctx, cephIngressNetworkPolicyTemplate.DeepCopy(), timeout, interval)
+			})
 		})
 		When("there is no value for the keys in smtp secret", func() {
 			It("should not create alertmanager config", func() {
@@ -1080,6 +1357,184 @@ var _ = Describe("ManagedOCS controller", func() {
 
 				utils.WaitForResource(k8sClient, ctx, amConfigTemplate.DeepCopy(), timeout, interval)
 			})
+			It("should set the AlertingConfigured condition to true", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionAlertingConfigured)
+				}, timeout, interval).Should(BeTrue())
+			})
+		})
+		When("the pagerduty secret holds an Events API v2 routing key and a cluster ID", func() {
+			It("should configure the routing key and add the cluster details", func() {
+				pdSecret := pdSecretTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(pdSecret), pdSecret)).Should(Succeed())
+				pdSecret.Data["ROUTING_KEY"] = []byte("test-routing-key")
+				pdSecret.Data["CLUSTER_ID"] = []byte("test-cluster-id")
+				Expect(k8sClient.Update(ctx, pdSecret)).Should(Succeed())
+
+				amconfig := amConfigTemplate.DeepCopy()
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+					for _, receiver := range amconfig.Spec.Receivers {
+						if receiver.Name != "pagerduty" {
+							continue
+						}
+						config := receiver.PagerDutyConfigs[0]
+						hasClusterID := false
+						for _, detail := range config.Details {
+							if detail.Key == "cluster_id" && detail.Value == "test-cluster-id" {
+								hasClusterID = true
+							}
+						}
+						return config.ServiceKey == nil && config.RoutingKey != nil &&
+							config.RoutingKey.Key == "ROUTING_KEY" && config.Severity != "" && hasClusterID
+					}
+					return false
+				}, timeout, interval).Should(BeTrue())
+
+				// Restore the legacy service key for future cases
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(pdSecret), pdSecret)).Should(Succeed())
+				delete(pdSecret.Data, "ROUTING_KEY")
+				delete(pdSecret.Data, "CLUSTER_ID")
+				Expect(k8sClient.Update(ctx, pdSecret)).Should(Succeed())
+			})
+		})
+		When("the DMS heartbeat interval is configured", func() {
+			It("should send the heartbeat at the configured interval", func() {
+				amconfig := amConfigTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+				found := false
+				for _, raw := range amconfig.Spec.Route.Routes {
+					route := promv1a1.Route{}
+					Expect(json.Unmarshal(raw.Raw, &route)).Should(Succeed())
+					if route.Receiver == "DeadMansSnitch" {
+						found = true
+						Expect(route.RepeatInterval).Should(Equal("60s"))
+						Expect(route.GroupInterval).Should(Equal("60s"))
+					}
+				}
+				Expect(found).Should(BeTrue())
+			})
+			It("should report a healthy heartbeat while alertmanager is starting", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionHeartbeatHealthy)
+				}, timeout, interval).Should(BeTrue())
+			})
+		})
+		When("the alertmanager config is rendered", func() {
+			It("should include the inhibition rules between related Ceph alerts", func() {
+				amconfig := amConfigTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+
+				inhibitedBy := map[string][]string{}
+				for _, rule := range amconfig.Spec.InhibitRules {
+					Expect(rule.SourceMatch).Should(HaveLen(1))
+					Expect(rule.TargetMatch).Should(HaveLen(1))
+					Expect(rule.Equal).Should(ContainElement("namespace"))
+					source := rule.SourceMatch[0].Value
+					inhibitedBy[source] = append(inhibitedBy[source], rule.TargetMatch[0].Value)
+				}
+				Expect(inhibitedBy["CephClusterErrorState"]).Should(ContainElement(ContainSubstring("^CephClusterWarningState$")))
+				Expect(inhibitedBy["CephNodeDown"]).Should(ContainElement(ContainSubstring("^CephOSDDiskUnavailable$")))
+				Expect(inhibitedBy["CephClusterReadOnly"]).Should(ContainElement(ContainSubstring("^CephClusterNearFull$")))
+			})
+		})
+		When("the alert routing policy ConfigMap overrides a route", func() {
+			It("should render the merged route into the alertmanager config", func() {
+				configMap := alertRoutingPolicyConfigMapTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(configMap), configMap)).Should(Succeed())
+				configMap.Data = map[string]string{
+					alertRoutingPolicyConfigMapKey: "routes:\n- receiver: pagerduty\n  repeatInterval: 1h\n",
+				}
+				Expect(k8sClient.Update(ctx, configMap)).Should(Succeed())
+
+				amconfig := amConfigTemplate.DeepCopy()
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+					for _, raw := range amconfig.Spec.Route.Routes {
+						route := promv1a1.Route{}
+						Expect(json.Unmarshal(raw.Raw, &route)).Should(Succeed())
+						if route.Receiver == "pagerduty" {
+							return route.RepeatInterval
+						}
+					}
+					return ""
+				}, timeout, interval).Should(Equal("1h"))
+			})
+		})
+		When("the alert routing policy ConfigMap holds an invalid policy", func() {
+			It("should report the failure and keep the alertmanager config", func() {
+				configMap := alertRoutingPolicyConfigMapTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(configMap), configMap)).Should(Succeed())
+				configMap.Data = map[string]string{
+					alertRoutingPolicyConfigMapKey: "routes:\n- receiver: unknown\n",
+				}
+				Expect(k8sClient.Update(ctx, configMap)).Should(Succeed())
+
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					for _, phase := range managedOCS.Status.ReconcilePhases {
+						if phase.Name == "AlertRoutingPolicy" {
+							return phase.Message
+						}
+					}
+					return ""
+				}, timeout, interval).Should(ContainSubstring("unknown receiver"))
+				Expect(meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionAlertingConfigured)).Should(BeTrue())
+
+				// Remove the policy for future cases
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(configMap), configMap)).Should(Succeed())
+				configMap.Data = map[string]string{}
+				Expect(k8sClient.Update(ctx, configMap)).Should(Succeed())
+			})
+		})
+		When("a maintenance window is active", func() {
+			It("should silence the matching alerts and report the window in status", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+				managedOCS.Spec.MaintenanceWindows = []v1.MaintenanceWindow{{
+					Start:    metav1.NewTime(time.Now().Add(-time.Minute)),
+					End:      metav1.NewTime(time.Now().Add(time.Hour)),
+					Matchers: []v1.AlertMatcher{{Name: "alertname", Value: "CephNodeDown"}},
+					Reason:   "node replacement",
+				}}
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+
+				amconfig := amConfigTemplate.DeepCopy()
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+					for _, raw := range amconfig.Spec.Route.Routes {
+						route := promv1a1.Route{}
+						Expect(json.Unmarshal(raw.Raw, &route)).Should(Succeed())
+						if route.Receiver == "null" && len(route.Matchers) == 1 && route.Matchers[0].Value == "CephNodeDown" {
+							return true
+						}
+					}
+					return false
+				}, timeout, interval).Should(BeTrue())
+
+				Eventually(func() int {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return len(managedOCS.Status.ActiveMaintenanceWindows)
+				}, timeout, interval).Should(Equal(1))
+
+				// Remove the window for future cases
+				Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+				managedOCS.Spec.MaintenanceWindows = nil
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+				Eventually(func() int {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return len(managedOCS.Status.ActiveMaintenanceWindows)
+				}, timeout, interval).Should(Equal(0))
+			})
 		})
 		When("a Grafana datasources secret exists in the openshift-monitoring namespace", func() {
 			It("should create k8sMetricsServiceMonitorAuthSecret in primary namespace", func() {
@@ -1124,6 +1579,8 @@ var _ = Describe("ManagedOCS controller", func() {
 
 				Expect(dmsPromRuleTemplate.Spec.Groups[0].Rules[0].Labels["alertname"]).Should(Equal("DeadMansSnitch"))
 				Expect(dmsPromRuleTemplate.Spec.Groups[0].Rules[0].Labels["namespace"]).Should(Equal(testPrimaryNamespace))
+				Expect(dmsPromRuleTemplate.Spec.Groups[0].Rules[0].Labels["cluster_id"]).Should(Equal(testClusterID))
+				Expect(dmsPromRuleTemplate.Spec.Groups[0].Rules[0].Labels["addon_name"]).Should(Equal(testAddonName))
 
 			})
 		})
@@ -1285,6 +1742,17 @@ var _ = Describe("ManagedOCS controller", func() {
 					interval,
 				)
 			})
+			It("should render the per alert customer notification templates", func() {
+				amconfig := amConfigTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+				for _, receiver := range amconfig.Spec.Receivers {
+					if receiver.Name == "SendGrid" {
+						Expect(receiver.EmailConfigs).Should(HaveLen(1))
+						Expect(receiver.EmailConfigs[0].HTML).Should(ContainSubstring(`if eq .Labels.alertname "CephClusterNearFull"`))
+						Expect(receiver.EmailConfigs[0].Headers[0].Value).Should(ContainSubstring(`if eq .CommonLabels.alertname "CephClusterReadOnly"`))
+					}
+				}
+			})
 		})
 		When("notification email address in the add-on parameter is updated", func() {
 			It("should update alertmanager config with the updated notification email", func() {
@@ -1359,6 +1827,90 @@ var _ = Describe("ManagedOCS controller", func() {
 				)
 			})
 		})
+		When("notification email addresses in the add-on parameter are sparse, duplicated or invalid", func() {
+			It("should update alertmanager config with the unique valid addresses only", func() {
+				secret := addonParamsSecretTemplate.DeepCopy()
+				secretKey := utils.GetResourceKey(secret)
+				Expect(k8sClient.Get(ctx, secretKey, secret)).Should(Succeed())
+
+				// Add notification emails after a gap in the indices
+				secret.Data["notification-email-2"] = []byte("test-2@email.com")
+				secret.Data["notification-email-3"] = []byte("TEST-0@email.com")
+				secret.Data["notification-email-4"] = []byte("not-an-email")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+
+				amconfig := amConfigTemplate.DeepCopy()
+				amconfigKey := utils.GetResourceKey(amconfig)
+				utils.WaitForAlertManagerSMTPReceiverEmailConfigToUpdate(
+					k8sClient,
+					ctx,
+					amconfigKey,
+					[]string{"test-0@email.com", "test-2@email.com"},
+					"SendGrid",
+					timeout,
+					interval,
+				)
+			})
+			It("should report the invalid addresses in the ManagedOCS status", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() []string {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					keys := []string{}
+					for _, rejected := range managedOCS.Status.RejectedNotificationEmails {
+						keys = append(keys, rejected.Key)
+					}
+					return keys
+				}, timeout, interval).Should(Equal([]string{"notification-email-4"}))
+
+				// Remove the added notification emails from the addon param secret
+				secret := addonParamsSecretTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(secret), secret)).Should(Succeed())
+				delete(secret.Data, "notification-email-2")
+				delete(secret.Data, "notification-email-3")
+				delete(secret.Data, "notification-email-4")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+			})
+		})
+		When("resolved notifications and the daily digest are enabled in the add-on parameters", func() {
+			It("should send resolved notifications and route non-critical alerts to the digest", func() {
+				secret := addonParamsSecretTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(secret), secret)).Should(Succeed())
+				secret.Data["notification-send-resolved"] = []byte("true")
+				secret.Data["notification-digest"] = []byte("true")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+
+				amconfig := amConfigTemplate.DeepCopy()
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+					sendResolved := false
+					for _, receiver := range amconfig.Spec.Receivers {
+						if receiver.Name == "SendGrid" && len(receiver.EmailConfigs) > 0 {
+							sendResolved = *receiver.EmailConfigs[0].SendResolved
+						}
+					}
+					digestRouted := false
+					for _, raw := range amconfig.Spec.Route.Routes {
+						route := promv1a1.Route{}
+						Expect(json.Unmarshal(raw.Raw, &route)).Should(Succeed())
+						if route.Receiver == "SendGridDigest" {
+							// The first digest is sent a day after the first alert, then daily
+							Expect(route.GroupWait).To(Equal("24h"))
+							Expect(route.GroupInterval).To(Equal("24h"))
+							Expect(route.RepeatInterval).To(Equal("24h"))
+							digestRouted = true
+						}
+					}
+					return sendResolved && digestRouted
+				}, timeout, interval).Should(BeTrue())
+
+				// Restore the defaults for future cases
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(secret), secret)).Should(Succeed())
+				delete(secret.Data, "notification-send-resolved")
+				delete(secret.Data, "notification-digest")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+			})
+		})
 		When("there is no notification email address in the add-on parameter", func() {
 			It("should update alertmanager config by removing the SMTP email configs", func() {
 				secret := addonParamsSecretTemplate.DeepCopy()
@@ -1447,6 +1999,43 @@ var _ = Describe("ManagedOCS controller", func() {
 				}, timeout, interval).Should(Equal(true))
 			})
 		})
+		When("a slack secret is created", func() {
+			It("should configure the slack receiver and allow egress to the slack host", func() {
+				slackSecret := &corev1.Secret{}
+				slackSecret.Name = testSlackSecretName
+				slackSecret.Namespace = testPrimaryNamespace
+				slackSecret.Data = map[string][]byte{
+					"WEBHOOK_URL": []byte("https://hooks.slack.test/services/T0/B0/X0"),
+					"CHANNEL":     []byte("#storage-alerts"),
+				}
+				Expect(k8sClient.Create(ctx, slackSecret)).Should(Succeed())
+
+				amconfig := amConfigTemplate.DeepCopy()
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(amconfig), amconfig)).Should(Succeed())
+					for _, receiver := range amconfig.Spec.Receivers {
+						if receiver.Name == "slack" && len(receiver.SlackConfigs) > 0 {
+							return receiver.SlackConfigs[0].Channel
+						}
+					}
+					return ""
+				}, timeout, interval).Should(Equal("#storage-alerts"))
+
+				Eventually(func() bool {
+					egress := egressNetworkPolicyTemplate.DeepCopy()
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(egress), egress)).Should(Succeed())
+					for _, egressRule := range egress.Spec.Egress {
+						if egressRule.To.DNSName == "hooks.slack.test" {
+							return true
+						}
+					}
+					return false
+				}, timeout, interval).Should(BeTrue())
+
+				// Remove the secret for future cases
+				Expect(k8sClient.Delete(ctx, slackSecret)).Should(Succeed())
+			})
+		})
 		When("the EgressNetworkPolicy resource is deleted", func() {
 			It("should create a new EgressNetworkPolicy in the namespace", func() {
 				// Delete the EgressNetworkPolicy resource
@@ -1514,6 +2103,11 @@ var _ = Describe("ManagedOCS controller", func() {
 				utils.WaitForResource(k8sClient, ctx, cephIngressNetworkPolicyTemplate.DeepCopy(), timeout, interval)
 			})
 		})
+		When("the deployment type is converged", func() {
+			It("should not create the provider ingress NetworkPolicy", func() {
+				utils.EnsureNoResource(k8sClient, ctx, providerIngressNetworkPolicyTemplate.DeepCopy(), timeout, interval)
+			})
+		})
 		When("the addon config map does not exist while all other uninstall conditions are met", func() {
 			It("should not delete the managedOCS resource", func() {
 				setupUninstallConditions(false, testAddonConfigMapDeleteLabelKey, true, true, true, false, false)
@@ -1557,6 +2151,14 @@ var _ = Describe("ManagedOCS controller", func() {
 					return k8sClient.Get(ctx, key, managedOCS)
 				}, timeout, interval).Should(Succeed())
 			})
+			It("should report that the uninstall is blocked", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return meta.IsStatusConditionTrue(managedOCS.Status.Conditions, v1.ConditionUninstallBlocked)
+				}, timeout, interval).Should(BeTrue())
+			})
 		})
 		When("prometheus is not ready while all other uninstall conditions are met", func() {
 			It("should not delete the managedOCS resource", func() {
@@ -1589,6 +2191,24 @@ var _ = Describe("ManagedOCS controller", func() {
 				Consistently(func() error {
 					return k8sClient.Get(ctx, key, managedOCS)
 				}, timeout, interval).Should(Succeed())
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "UninstallBlocked", timeout, interval)
+			})
+			It("should report the blocking resources in the ManagedOCS status", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() *v1.UninstallStatus {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					return managedOCS.Status.Uninstall
+				}, timeout, interval).Should(And(
+					Not(BeNil()),
+					WithTransform(func(s *v1.UninstallStatus) v1.UninstallPhase { return s.Phase }, Equal(v1.UninstallBlockedOnPVCs)),
+					WithTransform(func(s *v1.UninstallStatus) []v1.BlockingResource { return s.BlockingResources }, ContainElement(v1.BlockingResource{
+						Kind:      v1.BlockingPersistentVolumeClaim,
+						Namespace: pvc1Template.Namespace,
+						Name:      pvc1Template.Name,
+						Class:     pvc1StorageClassName,
+					})),
+				))
 			})
 		})
 		When("there are pvcs in a secondary namespace while all other uninstall conditions are met", func() {
@@ -1602,6 +2222,108 @@ var _ = Describe("ManagedOCS controller", func() {
 				}, timeout, interval).Should(Succeed())
 			})
 		})
+		When("there is a retained OCS volume while all other uninstall conditions are met", func() {
+			It("should report the volume and ignore claims without a storage class", func() {
+				Expect(k8sClient.Create(ctx, retainedPVTemplate.DeepCopy())).Should(Succeed())
+				Expect(k8sClient.Create(ctx, classlessPVCTemplate.DeepCopy())).Should(Succeed())
+				setupUninstallConditions(true, testAddonConfigMapDeleteLabelKey, true, true, true, false, false)
+
+				managedOCS := managedOCSTemplate.DeepCopy()
+				key := utils.GetResourceKey(managedOCS)
+				Eventually(func() []v1.BlockingResource {
+					Expect(k8sClient.Get(ctx, key, managedOCS)).Should(Succeed())
+					if managedOCS.Status.Uninstall == nil {
+						return nil
+					}
+					return managedOCS.Status.Uninstall.BlockingResources
+				}, timeout, interval).Should(Equal([]v1.BlockingResource{{
+					Kind:  v1.BlockingPersistentVolume,
+					Name:  retainedPVTemplate.Name,
+					Class: storageClassRbdName,
+				}}))
+
+				// Keep the uninstall blocked while the blocking resources are removed
+				setupUninstallConditions(true, testAddonConfigMapDeleteLabelKey, true, true, false, false, false)
+				for _, obj := range []client.Object{retainedPVTemplate.DeepCopy(), classlessPVCTemplate.DeepCopy()} {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(obj), obj)).Should(Succeed())
+					obj.SetFinalizers(nil)
+					Expect(k8sClient.Update(ctx, obj)).Should(Succeed())
+					Expect(k8sClient.Delete(ctx, obj)).Should(Succeed())
+					utils.EnsureNoResource(k8sClient, ctx, obj, timeout, interval)
+				}
+			})
+		})
+		When("a force uninstall is requested while pvcs block the uninstall", func() {
+			It("should reject a request without a valid acknowledgement", func() {
+				setupUninstallConditions(true, testAddonConfigMapDeleteLabelKey, true, true, true, true, false)
+
+				managedOCS := managedOCSTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+				managedOCS.SetAnnotations(map[string]string{
+					"ocs.openshift.io/force-uninstall":        "yes",
+					"ocs.openshift.io/force-uninstall-reason": "abandoned cluster",
+				})
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "ForceUninstallRejected", timeout, interval)
+				pvc := pvc1Template.DeepCopy()
+				Consistently(func() bool {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(pvc), pvc)).Should(Succeed())
+					return pvc.DeletionTimestamp.IsZero()
+				}, timeout, interval).Should(BeTrue())
+			})
+			It("should delete the blocking pvcs after the grace period", func() {
+				managedOCS := managedOCSTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+				managedOCS.SetAnnotations(map[string]string{
+					"ocs.openshift.io/force-uninstall":        string(managedOCS.UID),
+					"ocs.openshift.io/force-uninstall-reason": "abandoned cluster",
+				})
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+
+				// The customer can't be warned without a notification email
+				Eventually(func() string {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+					if managedOCS.Status.Uninstall == nil {
+						return ""
+					}
+					return managedOCS.Status.Uninstall.Message
+				}, timeout, interval).Should(ContainSubstring("customer notification email"))
+				utils.EnsureNoEvent(k8sClient, ctx, managedOCS, "ForceUninstallScheduled", timeout, interval)
+
+				secret := addonParamsSecretTemplate.DeepCopy()
+				Expect(k8sClient.Get(ctx, utils.GetResourceKey(secret), secret)).Should(Succeed())
+				secret.Data["notification-email-0"] = []byte("test-0@email.com")
+				Expect(k8sClient.Update(ctx, secret)).Should(Succeed())
+
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "ForceUninstallScheduled", timeout, interval)
+				pvc := pvc1Template.DeepCopy()
+				Eventually(func() bool {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(pvc), pvc)).Should(Succeed())
+					return pvc.DeletionTimestamp.IsZero()
+				}, timeout, interval).Should(BeFalse())
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "ForceUninstallDeletingResources", timeout, interval)
+
+				Eventually(func() *v1.ForceUninstallStatus {
+					Expect(k8sClient.Get(ctx, utils.GetResourceKey(managedOCS), managedOCS)).Should(Succeed())
+					if managedOCS.Status.Uninstall == nil {
+						return nil
+					}
+					return managedOCS.Status.Uninstall.Force
+				}, timeout, interval).Should(And(
+					Not(BeNil()),
+					WithTransform(func(s *v1.ForceUninstallStatus) string { return s.Reason }, Equal("abandoned cluster")),
+					WithTransform(func(s *v1.ForceUninstallStatus) int { return s.DeletedResourceCount }, Equal(1)),
+				))
+
+				// Remove the force uninstall request and let the terminating pvc go
+				managedOCS.SetAnnotations(nil)
+				Expect(k8sClient.Update(ctx, managedOCS)).Should(Succeed())
+				pvc.SetFinalizers(nil)
+				Expect(k8sClient.Update(ctx, pvc)).Should(Succeed())
+				utils.EnsureNoResource(k8sClient, ctx, pvc1Template.DeepCopy(), timeout, interval)
+			})
+		})
 		When("All uninstall conditions are met", func() {
 			It("should delete the managedOCS", func() {
 				setupUninstallConditions(true, testAddonConfigMapDeleteLabelKey, true, true, true, false, false)
@@ -1612,6 +2334,8 @@ var _ = Describe("ManagedOCS controller", func() {
 					err := k8sClient.Get(ctx, key, managedOCS)
 					return err != nil && errors.IsNotFound(err)
 				}, timeout, interval).Should(BeTrue())
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "UninstallStarted", timeout, interval)
+				utils.WaitForEvent(k8sClient, ctx, managedOCS, "FinalizerRemoved", timeout, interval)
 			})
 			It("should delete the deployer csv", func() {
 				csv := csvTemplate.DeepCopy()
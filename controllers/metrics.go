@@ -0,0 +1,118 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+)
+
+var (
+	driftCorrectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "managedocs_drift_corrections_total",
+			Help: "Number of times the spec of an operator owned resource was reverted to its desired state",
+		},
+		[]string{"resource"},
+	)
+
+	componentState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "managedocs_component_state",
+			Help: "State of the managed components, set to 1 for the current state of each component and 0 otherwise",
+		},
+		[]string{"component", "state"},
+	)
+
+	reconcilePhaseErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "managedocs_reconcile_phase_errors_total",
+			Help: "Number of failed runs of each reconcile phase",
+		},
+		[]string{"phase"},
+	)
+
+	reconcilePhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "managedocs_reconcile_phase_duration_seconds",
+			Help:    "Duration of the runs of each reconcile phase",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"phase"},
+	)
+
+	storageDeviceSetCount = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "managedocs_storage_device_set_count",
+			Help: "Requested (desired) and current (actual) count of the default storage device set",
+		},
+		[]string{"type"},
+	)
+
+	uninstallBlockingResources = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "managedocs_uninstall_blocking_resources",
+			Help: "Number of consumer resources of each kind blocking a requested uninstall",
+		},
+		[]string{"kind"},
+	)
+
+	forceUninstallDeletionTime = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "managedocs_force_uninstall_deletion_timestamp_seconds",
+			Help: "Time after which the resources blocking a forced uninstall are deleted, 0 when no forced uninstall is scheduled",
+		},
+	)
+)
+
+var componentStates = []v1.ComponentState{
+	v1.ComponentReady,
+	v1.ComponentPending,
+	v1.ComponentNotFound,
+	v1.ComponentUnknown,
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		driftCorrectionsTotal,
+		componentState,
+		reconcilePhaseErrorsTotal,
+		reconcilePhaseDuration,
+		storageDeviceSetCount,
+		uninstallBlockingResources,
+		forceUninstallDeletionTime,
+	)
+}
+
+// setComponentStateMetric marks the given state as the current state of the component
+func setComponentStateMetric(component string, state v1.ComponentState) {
+	for _, s := range componentStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		componentState.WithLabelValues(component, string(s)).Set(value)
+	}
+}
+
+func setUninstallBlockingResourcesMetric(blockers []v1.BlockingResource) {
+	for kind, count := range countBlockingResources(blockers) {
+		uninstallBlockingResources.WithLabelValues(string(kind)).Set(float64(count))
+	}
+}
@@ -0,0 +1,100 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+)
+
+// getEffectiveReconcileStrategies resolves the reconcile strategy of each component.
+// Unset components fall back to strict, except for the storage cluster which falls back
+// to the global reconcile strategy. Invalid values are treated as strict.
+func (r *ManagedOCSReconciler) getEffectiveReconcileStrategies() v1.ComponentReconcileStrategyMap {
+	overrides := r.managedOCS.Spec.ComponentReconcileStrategies
+	parse := func(component string, strategy v1.ReconcileStrategy, fallback v1.ReconcileStrategy) v1.ReconcileStrategy {
+		if strategy == "" {
+			return fallback
+		}
+		parsed, err := v1.ParseReconcileStrategy(string(strategy))
+		if err != nil {
+			r.Log.V(-1).Info("Unknown component reconcile strategy, falling back to strict", "component", component, "reconcileStrategy", strategy)
+			return v1.ReconcileStrategyStrict
+		}
+		return parsed
+	}
+
+	return v1.ComponentReconcileStrategyMap{
+		StorageCluster:     parse("storageCluster", overrides.StorageCluster, r.reconcileStrategy),
+		Prometheus:         parse("prometheus", overrides.Prometheus, v1.ReconcileStrategyStrict),
+		Alertmanager:       parse("alertmanager", overrides.Alertmanager, v1.ReconcileStrategyStrict),
+		AlertmanagerConfig: parse("alertmanagerConfig", overrides.AlertmanagerConfig, v1.ReconcileStrategyStrict),
+		NetworkPolicies:    parse("networkPolicies", overrides.NetworkPolicies, v1.ReconcileStrategyStrict),
+		CSVResources:       parse("csvResources", overrides.CSVResources, v1.ReconcileStrategyStrict),
+	}
+}
+
+// applyReconcileStrategy reconciles the desired spec of obj with the live spec, as it was
+// before mutation, according to the given strategy. In strict mode the desired spec is
+// kept as is, in none mode the live spec is restored and in merge mode the desired fields
+// are applied on top of the live spec.
+func applyReconcileStrategy(obj client.Object, live map[string]interface{}, strategy v1.ReconcileStrategy) error {
+	if strategy == v1.ReconcileStrategyStrict || live == nil {
+		return nil
+	}
+
+	desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	if strategy == v1.ReconcileStrategyMerge {
+		desired["spec"] = mergeFields(live["spec"], desired["spec"])
+	} else {
+		desired["spec"] = live["spec"]
+	}
+
+	// Reset the object so that fields removed from the spec are not kept
+	value := reflect.ValueOf(obj).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(desired, obj)
+}
+
+// mergeFields applies the desired value on top of the live value. Maps are merged key by
+// key so that keys only found in the live value are kept, any other value is replaced.
+func mergeFields(live, desired interface{}) interface{} {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if !liveIsMap || !desiredIsMap {
+		if desired == nil {
+			return live
+		}
+		return desired
+	}
+
+	merged := make(map[string]interface{}, len(liveMap))
+	for key, value := range liveMap {
+		merged[key] = value
+	}
+	for key, value := range desiredMap {
+		merged[key] = mergeFields(liveMap[key], value)
+	}
+	return merged
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	ctrlutils "github.com/red-hat-storage/ocs-osd-deployer/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("Reconcile strategies", func() {
+	It("should merge the desired fields on top of the live fields", func() {
+		for _, test := range []struct {
+			name     string
+			live     interface{}
+			desired  interface{}
+			expected interface{}
+		}{{
+			name:     "desired scalar replaces the live scalar",
+			live:     int64(3),
+			desired:  int64(1),
+			expected: int64(1),
+		}, {
+			name:     "live value is kept when there is no desired value",
+			live:     "live",
+			desired:  nil,
+			expected: "live",
+		}, {
+			name:     "live only keys are kept",
+			live:     map[string]interface{}{"replicas": int64(3), "paused": true},
+			desired:  map[string]interface{}{"replicas": int64(1)},
+			expected: map[string]interface{}{"replicas": int64(1), "paused": true},
+		}, {
+			name: "nested maps are merged key by key",
+			live: map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": "1", "ephemeral-storage": "1Gi"},
+			},
+			desired: map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": "2", "memory": "1Gi"},
+			},
+			expected: map[string]interface{}{
+				"limits": map[string]interface{}{"cpu": "2", "memory": "1Gi", "ephemeral-storage": "1Gi"},
+			},
+		}, {
+			name:     "lists are replaced as a whole",
+			live:     map[string]interface{}{"args": []interface{}{"a", "b"}},
+			desired:  map[string]interface{}{"args": []interface{}{"c"}},
+			expected: map[string]interface{}{"args": []interface{}{"c"}},
+		}, {
+			name:     "desired map replaces a live value of another type",
+			live:     "app=test",
+			desired:  map[string]interface{}{"app": "test"},
+			expected: map[string]interface{}{"app": "test"},
+		}} {
+			By(test.name)
+			Expect(mergeFields(test.live, test.desired)).To(Equal(test.expected), test.name)
+		}
+	})
+
+	Context("applied to a resource spec", func() {
+		var live map[string]interface{}
+
+		// newDesired returns the object as mutated by a reconcile, with a different spec
+		// than the live object and without the field only set on the live object
+		newDesired := func() *promv1.Prometheus {
+			desired := &promv1.Prometheus{}
+			desired.Name = "test-prometheus"
+			replicas := int32(1)
+			desired.Spec.Replicas = &replicas
+			desired.Spec.ExternalLabels = map[string]string{"cluster": "desired"}
+			return desired
+		}
+
+		BeforeEach(func() {
+			prom := &promv1.Prometheus{}
+			prom.Name = "test-prometheus"
+			replicas := int32(3)
+			prom.Spec.Replicas = &replicas
+			prom.Spec.LogLevel = "debug"
+			prom.Spec.ExternalLabels = map[string]string{"cluster": "live", "team": "sre"}
+			var err error
+			live, err = runtime.DefaultUnstructuredConverter.ToUnstructured(prom)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should keep the desired spec in strict mode", func() {
+			desired := newDesired()
+			Expect(applyReconcileStrategy(desired, live, v1.ReconcileStrategyStrict)).Should(Succeed())
+			Expect(desired).To(Equal(newDesired()))
+		})
+		It("should restore the live spec in none mode", func() {
+			desired := newDesired()
+			Expect(applyReconcileStrategy(desired, live, v1.ReconcileStrategyNone)).Should(Succeed())
+			Expect(*desired.Spec.Replicas).To(Equal(int32(3)))
+			Expect(desired.Spec.LogLevel).To(Equal("debug"))
+			Expect(desired.Spec.ExternalLabels).To(Equal(map[string]string{"cluster": "live", "team": "sre"}))
+		})
+		It("should apply the desired spec on top of the live spec in merge mode", func() {
+			desired := newDesired()
+			Expect(applyReconcileStrategy(desired, live, v1.ReconcileStrategyMerge)).Should(Succeed())
+			Expect(*desired.Spec.Replicas).To(Equal(int32(1)))
+			Expect(desired.Spec.LogLevel).To(Equal("debug"))
+			Expect(desired.Spec.ExternalLabels).To(Equal(map[string]string{"cluster": "desired", "team": "sre"}))
+		})
+		It("should keep the desired spec of a new resource", func() {
+			desired := newDesired()
+			Expect(applyReconcileStrategy(desired, nil, v1.ReconcileStrategyNone)).Should(Succeed())
+			Expect(desired).To(Equal(newDesired()))
+		})
+	})
+
+	Context("applied to the CSV container resources", func() {
+		var r *ManagedOCSReconciler
+
+		// The live container sets its own limits and an extra resource
+		newContainer := func() *corev1.Container {
+			return &corev1.Container{
+				Name: "ocs-operator",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						"cpu":               resource.MustParse("5"),
+						"ephemeral-storage": resource.MustParse("1Gi"),
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			r = newTestReconciler(ctrlutils.NewDefaultAddonParams())
+		})
+
+		It("should replace the resources in strict mode", func() {
+			r.reconcileStrategies.CSVResources = v1.ReconcileStrategyStrict
+			resources, err := r.getDesiredCSVContainerResources(newContainer())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(Equal(ctrlutils.GetResourceRequirements("ocs-operator")))
+		})
+		It("should keep the resources the deployer does not set in merge mode", func() {
+			r.reconcileStrategies.CSVResources = v1.ReconcileStrategyMerge
+			resources, err := r.getDesiredCSVContainerResources(newContainer())
+			Expect(err).ToNot(HaveOccurred())
+
+			desired := ctrlutils.GetResourceRequirements("ocs-operator")
+			Expect(resources.Limits.Cpu().Equal(*desired.Limits.Cpu())).To(BeTrue())
+			Expect(resources.Limits.Memory().Equal(*desired.Limits.Memory())).To(BeTrue())
+			Expect(resources.Limits.StorageEphemeral().Equal(resource.MustParse("1Gi"))).To(BeTrue())
+			Expect(resources.Requests.Cpu().Equal(*desired.Requests.Cpu())).To(BeTrue())
+		})
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -28,6 +29,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -41,6 +43,8 @@ import (
 	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	ocsv1 "github.com/red-hat-storage/ocs-operator/api/v1"
 	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -54,17 +58,24 @@ var testEnv *envtest.Environment
 const (
 	testPrimaryNamespace                       = "primary"
 	testSecondaryNamespace                     = "secondary"
+	testAddonName                              = "test-addon"
+	testClusterID                              = "test-cluster-id"
+	testClusterName                            = "test-cluster"
+	testDMSHeartbeatInterval                   = time.Minute
+	testForceUninstallGracePeriod              = time.Second
 	testAddonParamsSecretName                  = "test-addon-secret"
 	testPagerdutySecretName                    = "test-pagerduty-secret"
 	testDeadMansSnitchSecretName               = "test-deadmanssnitch-secret"
 	testSMTPSecretName                         = "test-smtp-secret"
+	testOpsgenieSecretName                     = "test-opsgenie-secret"
+	testSlackSecretName                        = "test-slack-secret"
+	testWebhookSecretName                      = "test-webhook-secret"
 	testAddonConfigMapName                     = "test-addon-configmap"
 	testAddonConfigMapDeleteLabelKey           = "test-addon-configmap-delete-label-key"
 	testDeployerCSVName                        = "ocs-osd-deployer.x.y.z"
 	testGrafanaFederateSecretName              = "grafana-datasources"
 	testK8sMetricsServiceMonitorAuthSecretName = "k8s-metrics-service-monitor-auth"
 	testOpenshiftMonitoringNamespace           = "openshift-monitoring"
-	testCustomerNotificationHTMLPath           = "../templates/customernotification.html"
 	testDeploymentType                         = "converged"
 )
 
@@ -117,19 +128,32 @@ var _ = BeforeSuite(func(done Done) {
 	})
 	Expect(err).ToNot(HaveOccurred())
 
+	customerNotification, err := templates.NewCustomerNotification()
+	Expect(err).ToNot(HaveOccurred())
+
 	err = (&ManagedOCSReconciler{
 		Client:                       k8sManager.GetClient(),
 		UnrestrictedClient:           k8sManager.GetClient(),
 		Log:                          ctrl.Log.WithName("controllers").WithName("ManagedOCS"),
 		Scheme:                       scheme.Scheme,
+		Recorder:                     k8sManager.GetEventRecorderFor("managedocs-controller"),
+		AddonName:                    testAddonName,
+		ClusterID:                    testClusterID,
+		ClusterName:                  testClusterName,
 		AddonParamSecretName:         testAddonParamsSecretName,
 		AddonConfigMapName:           testAddonConfigMapName,
 		AddonConfigMapDeleteLabelKey: testAddonConfigMapDeleteLabelKey,
 		PagerdutySecretName:          testPagerdutySecretName,
 		DeadMansSnitchSecretName:     testDeadMansSnitchSecretName,
 		SMTPSecretName:               testSMTPSecretName,
-		CustomerNotificationHTMLPath: testCustomerNotificationHTMLPath,
+		OpsgenieSecretName:           testOpsgenieSecretName,
+		SlackSecretName:              testSlackSecretName,
+		WebhookSecretName:            testWebhookSecretName,
+		CustomerNotification:         customerNotification,
 		DeploymentType:               testDeploymentType,
+		DMSHeartbeatInterval:         testDMSHeartbeatInterval,
+		ForceUninstallGracePeriod:    testForceUninstallGracePeriod,
+		alertmanagerAlerts:           &fakeAlertmanagerAlerts{receivers: []string{templates.SendGridReceiverName}},
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
@@ -183,6 +207,13 @@ var _ = BeforeSuite(func(done Done) {
 	mcgCSV.Spec.InstallStrategy.StrategySpec.DeploymentSpecs = getMockMCGCSVDeploymentSpec()
 	Expect(k8sClient.Create(ctx, mcgCSV)).ShouldNot(HaveOccurred())
 
+	// Create a worker node that identifies the cloud provider
+	workerNode := &corev1.Node{}
+	workerNode.Name = "test-worker-node"
+	workerNode.Labels = map[string]string{workerNodeLabelKey: ""}
+	workerNode.Spec.ProviderID = "aws:///us-east-1a/i-0123456789abcdef0"
+	Expect(k8sClient.Create(ctx, workerNode)).ShouldNot(HaveOccurred())
+
 	// Create the ManagedOCS resource
 	managedOCS := &v1.ManagedOCS{}
 	managedOCS.Name = managedOCSName
@@ -298,3 +329,28 @@ func getMockMCGCSVDeploymentSpec() []opv1a1.StrategyDeploymentSpec {
 	}
 	return deploymentSpec
 }
+
+// newTestReconciler returns a reconciler that is not registered with the manager, set up
+// as at the start of a reconcile of the ManagedOCS resource. It is used to test parts of
+// the reconcile logic in isolation, with the test client and in-memory events.
+func newTestReconciler(addonParams *utils.AddonParams) *ManagedOCSReconciler {
+	provider := cloudProviderAWS
+	r := &ManagedOCSReconciler{
+		Client:             k8sClient,
+		UnrestrictedClient: k8sClient,
+		Log:                ctrl.Log.WithName("controllers").WithName("test"),
+		Scheme:             scheme.Scheme,
+		Recorder:           record.NewFakeRecorder(100),
+		DeploymentType:     testDeploymentType,
+		ctx:                context.Background(),
+		namespace:          testSecondaryNamespace,
+		managedOCS:         &v1.ManagedOCS{},
+		storageCluster:     &ocsv1.StorageCluster{},
+		addonParams:        addonParams,
+		deploymentProfile:  deploymentProfiles[testDeploymentType],
+		cloudProvider:      &provider,
+	}
+	r.managedOCS.Name = managedOCSName
+	r.managedOCS.Namespace = testSecondaryNamespace
+	return r
+}
@@ -0,0 +1,319 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+)
+
+const (
+	// Upper bound on the number of blocking resources listed in the ManagedOCS status
+	maxReportedBlockingResources = 20
+
+	// A blocked uninstall is checked again after the time it was already blocked
+	// for, within these bounds
+	minUninstallBlockedRequeueInterval = 10 * time.Second
+	maxUninstallBlockedRequeueInterval = 5 * time.Minute
+
+	uninstallBlockedWarningInterval = 30 * time.Minute
+
+	// A force uninstall is requested by setting these annotations on the ManagedOCS resource
+	// or on the add-on delete config map. As an explicit acknowledgement of the deletion of
+	// the blocking resources, the value of the force uninstall annotation must be the UID
+	// of the ManagedOCS resource, and a reason must be given.
+	forceUninstallAnnotation       = "ocs.openshift.io/force-uninstall"
+	forceUninstallReasonAnnotation = "ocs.openshift.io/force-uninstall-reason"
+
+	defaultForceUninstallGracePeriod = 24 * time.Hour
+)
+
+// alertmanagerAlertsProvider exposes the alerts of the managed Alertmanager instance
+type alertmanagerAlertsProvider interface {
+	// getAlertReceivers returns the receivers of the active alerts with the given name,
+	// silenced and inhibited alerts are not notified and are left out
+	getAlertReceivers(ctx context.Context, alertName string) ([]string, error)
+}
+
+// httpAlertmanagerAlerts reads the alerts API of the managed Alertmanager instance
+type httpAlertmanagerAlerts struct {
+	endpoint   string
+	httpClient *http.Client
+}
+
+func newHTTPAlertmanagerAlerts(namespace string) *httpAlertmanagerAlerts {
+	return &httpAlertmanagerAlerts{
+		endpoint:   fmt.Sprintf(alertmanagerServiceURLFormat, namespace),
+		httpClient: &http.Client{Timeout: alertmanagerQueryTimeout},
+	}
+}
+
+func (a *httpAlertmanagerAlerts) getAlertReceivers(ctx context.Context, alertName string) ([]string, error) {
+	query := url.Values{}
+	query.Set("filter", fmt.Sprintf("alertname=%q", alertName))
+	query.Set("active", "true")
+	query.Set("silenced", "false")
+	query.Set("inhibited", "false")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint+"/api/v2/alerts?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to query alertmanager: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("alertmanager alerts request failed with status %s", resp.Status)
+	}
+
+	alerts := []struct {
+		Receivers []struct {
+			Name string `json:"name"`
+		} `json:"receivers"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&alerts); err != nil {
+		return nil, fmt.Errorf("unable to read alertmanager alerts: %v", err)
+	}
+	receivers := []string{}
+	for i := range alerts {
+		for _, receiver := range alerts[i].Receivers {
+			receivers = append(receivers, receiver.Name)
+		}
+	}
+	return receivers, nil
+}
+
+// setUninstallPhase records the current phase of the uninstall in the ManagedOCS status
+func (r *ManagedOCSReconciler) setUninstallPhase(phase v1.UninstallPhase, message string) *v1.UninstallStatus {
+	status := r.managedOCS.Status.Uninstall
+	if status == nil || status.Phase != phase {
+		newStatus := &v1.UninstallStatus{
+			Phase:          phase,
+			PhaseStartTime: metav1.Now(),
+		}
+		if status != nil {
+			newStatus.Force = status.Force
+			newStatus.RejectedForceUninstallRequest = status.RejectedForceUninstallRequest
+		}
+		status = newStatus
+		r.managedOCS.Status.Uninstall = status
+	}
+	status.Message = message
+	return status
+}
+
+// reportBlockingResources records the resources that block the uninstall in the ManagedOCS
+// status, and periodically as a warning event. It returns the time after which the resources
+// should be checked again.
+func (r *ManagedOCSReconciler) reportBlockingResources(blockers []v1.BlockingResource) time.Duration {
+	status := r.setUninstallPhase(
+		v1.UninstallBlockedOnPVCs,
+		fmt.Sprintf("Waiting for %d consumer resources using OCS storage to be deleted", len(blockers)),
+	)
+	status.BlockingResourceCount = len(blockers)
+	if len(blockers) > maxReportedBlockingResources {
+		blockers = blockers[:maxReportedBlockingResources]
+	}
+	status.BlockingResources = blockers
+
+	now := time.Now()
+	if status.LastWarningTime == nil || now.Sub(status.LastWarningTime.Time) >= uninstallBlockedWarningInterval {
+		r.recordWarning(eventReasonUninstallBlocked,
+			"Uninstall is blocked by %d consumer resources using OCS storage, including %s %s",
+			status.BlockingResourceCount, blockers[0].Kind, blockingResourceName(blockers[0]))
+		lastWarningTime := metav1.NewTime(now)
+		status.LastWarningTime = &lastWarningTime
+	}
+
+	requeueAfter := now.Sub(status.PhaseStartTime.Time)
+	if requeueAfter < minUninstallBlockedRequeueInterval {
+		requeueAfter = minUninstallBlockedRequeueInterval
+	} else if requeueAfter > maxUninstallBlockedRequeueInterval {
+		requeueAfter = maxUninstallBlockedRequeueInterval
+	}
+	return requeueAfter
+}
+
+// getForceUninstallRequest returns the annotations that carry the force uninstall request,
+// or nil if no force uninstall was requested
+func (r *ManagedOCSReconciler) getForceUninstallRequest() map[string]string {
+	if _, found := r.managedOCS.Annotations[forceUninstallAnnotation]; found {
+		return r.managedOCS.Annotations
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMap.Name = r.AddonConfigMapName
+	configMap.Namespace = r.namespace
+	if err := r.get(configMap); err != nil {
+		if !errors.IsNotFound(err) {
+			r.Log.Error(err, "Unable to get addon delete configmap")
+		}
+		return nil
+	}
+	if _, found := configMap.Annotations[forceUninstallAnnotation]; found {
+		return configMap.Annotations
+	}
+	return nil
+}
+
+// isForceUninstallWarningSent checks whether the alert that warns the customer about a forced
+// uninstall is being notified through the customer email receiver. Alertmanager sends the
+// notification within the group wait of the receiver route.
+func (r *ManagedOCSReconciler) isForceUninstallWarningSent() (bool, error) {
+	if r.alertmanagerAlerts == nil {
+		r.alertmanagerAlerts = newHTTPAlertmanagerAlerts(r.namespace)
+	}
+	receivers, err := r.alertmanagerAlerts.getAlertReceivers(r.ctx, templates.ForceUninstallScheduledAlert)
+	if err != nil {
+		return false, err
+	}
+	for _, receiver := range receivers {
+		if isAlertmanagerConfigReceiver(receiver, templates.SendGridReceiverName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reconcileForceUninstall schedules the deletion of the resources that block the uninstall when
+// an acknowledged force uninstall is requested, and deletes them once the grace period is
+// over. The grace period starts once the customer warning is sent, which requires at least one
+// customer notification email. It returns the time after
+// which the request should be checked again, or 0 when no deletion is pending.
+func (r *ManagedOCSReconciler) reconcileForceUninstall(status *v1.UninstallStatus, blockers []v1.BlockingResource) (time.Duration, error) {
+	request := r.getForceUninstallRequest()
+	if request == nil {
+		if status.Force != nil {
+			r.recordEvent(eventReasonForceUninstallCancelled, "Force uninstall was cancelled")
+			status.Force = nil
+		}
+		status.RejectedForceUninstallRequest = ""
+		forceUninstallDeletionTime.Set(0)
+		return 0, nil
+	}
+
+	acknowledgement := request[forceUninstallAnnotation]
+	reason := request[forceUninstallReasonAnnotation]
+	if acknowledgement != string(r.managedOCS.UID) || reason == "" {
+		if status.RejectedForceUninstallRequest != acknowledgement {
+			r.recordWarning(eventReasonForceUninstallRejected,
+				"Force uninstall request ignored, the %s annotation must be set to the ManagedOCS UID and the %s annotation must hold a reason",
+				forceUninstallAnnotation, forceUninstallReasonAnnotation)
+			status.RejectedForceUninstallRequest = acknowledgement
+		}
+		status.Force = nil
+		forceUninstallDeletionTime.Set(0)
+		return 0, nil
+	}
+	status.RejectedForceUninstallRequest = ""
+
+	now := time.Now()
+	if status.Force == nil {
+		status.Force = &v1.ForceUninstallStatus{
+			RequestedTime: metav1.NewTime(now),
+		}
+		r.recordWarning(eventReasonForceUninstallAccepted,
+			"Force uninstall requested: %s. The %d resources blocking the uninstall will be deleted %s after the customer is warned",
+			reason, len(blockers), r.ForceUninstallGracePeriod)
+	}
+	status.Force.Reason = reason
+
+	if status.Force.DeletionTime == nil {
+		// Until the customer is warned, the alert carries the earliest possible deletion time
+		forceUninstallDeletionTime.Set(float64(now.Add(r.ForceUninstallGracePeriod).Unix()))
+		if len(r.addonParams.NotificationEmails) == 0 {
+			status.Message = fmt.Sprintf("Force uninstall requested, the deletion of %d blocking resources is blocked until a customer notification email is configured to receive the warning",
+				len(blockers))
+			return minUninstallBlockedRequeueInterval, nil
+		}
+		sent, err := r.isForceUninstallWarningSent()
+		if err != nil {
+			r.Log.V(-1).Info("Unable to check the force uninstall warning", "error", err.Error())
+		}
+		if !sent {
+			status.Message = fmt.Sprintf("Force uninstall requested, waiting for the customer to be warned before scheduling the deletion of %d blocking resources",
+				len(blockers))
+			return minUninstallBlockedRequeueInterval, nil
+		}
+		warningTime := metav1.NewTime(now)
+		deletionTime := metav1.NewTime(now.Add(r.ForceUninstallGracePeriod))
+		status.Force.WarningTime = &warningTime
+		status.Force.DeletionTime = &deletionTime
+		r.recordWarning(eventReasonForceUninstallScheduled,
+			"Customer was warned of the force uninstall. The %d resources blocking the uninstall will be deleted after %s",
+			len(blockers), deletionTime.Format(time.RFC3339))
+	}
+	forceUninstallDeletionTime.Set(float64(status.Force.DeletionTime.Unix()))
+
+	if remaining := status.Force.DeletionTime.Sub(now); remaining > 0 {
+		status.Message = fmt.Sprintf("Force uninstall scheduled, %d blocking resources will be deleted after %s",
+			len(blockers), status.Force.DeletionTime.Format(time.RFC3339))
+		return remaining, nil
+	}
+
+	deleted := 0
+	for i := range blockers {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(blockingResourceGVKs[blockers[i].Kind])
+		key := client.ObjectKey{Namespace: blockers[i].Namespace, Name: blockers[i].Name}
+		name := blockingResourceName(blockers[i])
+		if err := r.UnrestrictedClient.Get(r.ctx, key, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return 0, fmt.Errorf("Unable to get %s %s: %v", blockers[i].Kind, name, err)
+		}
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		r.Log.Info("Deleting resource blocking the force uninstall", "kind", blockers[i].Kind, "name", name)
+		if err := r.UnrestrictedClient.Delete(r.ctx, obj); err != nil && !errors.IsNotFound(err) {
+			return 0, fmt.Errorf("Unable to delete %s %s: %v", blockers[i].Kind, name, err)
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		status.Force.DeletedResourceCount += deleted
+		r.recordWarning(eventReasonForceUninstallDeleting, "Force uninstall deleted %d resources blocking the uninstall", deleted)
+	}
+	status.Message = fmt.Sprintf("Force uninstall in progress, waiting for %d resources to be deleted", len(blockers))
+	return minUninstallBlockedRequeueInterval, nil
+}
+
+// blockingResourceName returns the namespaced name of a blocking resource, or only its
+// name for cluster scoped resources
+func blockingResourceName(blocker v1.BlockingResource) string {
+	if blocker.Namespace == "" {
+		return blocker.Name
+	}
+	return blocker.Namespace + "/" + blocker.Name
+}
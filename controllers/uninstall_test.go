@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+type fakeAlertmanagerAlerts struct {
+	receivers []string
+	err       error
+}
+
+func (a *fakeAlertmanagerAlerts) getAlertReceivers(ctx context.Context, alertName string) ([]string, error) {
+	return a.receivers, a.err
+}
+
+var _ = Describe("Force uninstall", func() {
+	var (
+		r        *ManagedOCSReconciler
+		alerts   *fakeAlertmanagerAlerts
+		status   *v1.UninstallStatus
+		blockers []v1.BlockingResource
+	)
+
+	// The request is set on the ManagedOCS resource, the add-on delete config map is not read
+	setRequest := func(acknowledgement string, reason string) {
+		r.managedOCS.Annotations = map[string]string{
+			forceUninstallAnnotation:       acknowledgement,
+			forceUninstallReasonAnnotation: reason,
+		}
+	}
+
+	BeforeEach(func() {
+		alerts = &fakeAlertmanagerAlerts{}
+		r = newTestReconciler(utils.NewDefaultAddonParams())
+		r.managedOCS.UID = types.UID("test-managedocs-uid")
+		r.ForceUninstallGracePeriod = time.Hour
+		r.alertmanagerAlerts = alerts
+		status = &v1.UninstallStatus{Phase: v1.UninstallBlockedOnPVCs}
+		blockers = []v1.BlockingResource{{Kind: "PersistentVolumeClaim", Namespace: "test", Name: "test-pvc"}}
+	})
+
+	It("should report each rejected request once", func() {
+		recorder := r.Recorder.(*record.FakeRecorder)
+		for _, acknowledgement := range []string{"yes", "yes", "test-managedocs-uid", "test-managedocs-uid", "yes"} {
+			By(acknowledgement)
+			setRequest(acknowledgement, "")
+			Expect(r.reconcileForceUninstall(status, blockers)).To(BeZero())
+			Expect(status.Force).To(BeNil())
+			Expect(status.RejectedForceUninstallRequest).To(Equal(acknowledgement))
+		}
+		Expect(recorder.Events).To(HaveLen(3))
+		for i := 0; i < 3; i++ {
+			Expect(<-recorder.Events).To(ContainSubstring(eventReasonForceUninstallRejected))
+		}
+	})
+	It("should keep the deletion blocked without a customer notification email", func() {
+		setRequest("test-managedocs-uid", "abandoned cluster")
+		alerts.receivers = []string{"secondary/managed-ocs-alertmanager-config/" + templates.SendGridReceiverName}
+		Expect(r.reconcileForceUninstall(status, blockers)).To(Equal(minUninstallBlockedRequeueInterval))
+		Expect(status.Force).ToNot(BeNil())
+		Expect(status.Force.WarningTime).To(BeNil())
+		Expect(status.Force.DeletionTime).To(BeNil())
+		Expect(status.Message).To(ContainSubstring("customer notification email"))
+	})
+	It("should start the grace period once the customer warning is sent", func() {
+		setRequest("test-managedocs-uid", "abandoned cluster")
+		r.addonParams.NotificationEmails = []string{"customer@example.com"}
+
+		By("waiting for the alert to fire")
+		Expect(r.reconcileForceUninstall(status, blockers)).To(Equal(minUninstallBlockedRequeueInterval))
+		Expect(status.Force).ToNot(BeNil())
+		Expect(status.Force.Reason).To(Equal("abandoned cluster"))
+		Expect(status.Force.DeletionTime).To(BeNil())
+
+		By("waiting while alertmanager cannot be queried")
+		alerts.err = fmt.Errorf("alertmanager is unreachable")
+		Expect(r.reconcileForceUninstall(status, blockers)).To(Equal(minUninstallBlockedRequeueInterval))
+		Expect(status.Force.DeletionTime).To(BeNil())
+
+		By("waiting while the alert is only routed to other receivers")
+		alerts.err = nil
+		alerts.receivers = []string{"secondary/managed-ocs-alertmanager-config/" + templates.SendGridDigestReceiverName}
+		Expect(r.reconcileForceUninstall(status, blockers)).To(Equal(minUninstallBlockedRequeueInterval))
+		Expect(status.Force.DeletionTime).To(BeNil())
+
+		By("scheduling the deletion once the alert is sent to the customer")
+		alerts.receivers = append(alerts.receivers, "secondary/managed-ocs-alertmanager-config/"+templates.SendGridReceiverName)
+		requeueAfter, err := r.reconcileForceUninstall(status, blockers)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requeueAfter).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(status.Force.WarningTime).ToNot(BeNil())
+		Expect(status.Force.DeletionTime).ToNot(BeNil())
+		Expect(status.Force.DeletionTime.Sub(status.Force.WarningTime.Time)).To(Equal(time.Hour))
+
+		By("keeping the deletion time once the alert resolves")
+		deletionTime := *status.Force.DeletionTime
+		alerts.receivers = nil
+		Expect(r.reconcileForceUninstall(status, blockers)).To(BeNumerically(">", 0))
+		Expect(*status.Force.DeletionTime).To(Equal(deletionTime))
+	})
+
+	It("should read the receivers of the active alerts", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer GinkgoRecover()
+			Expect(req.URL.Path).To(Equal("/api/v2/alerts"))
+			Expect(req.URL.Query().Get("filter")).To(Equal(`alertname="` + templates.ForceUninstallScheduledAlert + `"`))
+			Expect(req.URL.Query().Get("silenced")).To(Equal("false"))
+			fmt.Fprint(w, `[{"labels":{"alertname":"ManagedOCSForceUninstallScheduled"},"status":{"state":"active"},
+"receivers":[{"name":"openshift-storage/managed-ocs-alertmanager-config/SendGrid"},{"name":"openshift-storage/managed-ocs-alertmanager-config/slack"}]}]`)
+		}))
+		defer server.Close()
+
+		provider := &httpAlertmanagerAlerts{endpoint: server.URL, httpClient: server.Client()}
+		Expect(provider.getAlertReceivers(context.Background(), templates.ForceUninstallScheduledAlert)).To(ConsistOf(
+			"openshift-storage/managed-ocs-alertmanager-config/SendGrid",
+			"openshift-storage/managed-ocs-alertmanager-config/slack",
+		))
+	})
+})
@@ -0,0 +1,202 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
+)
+
+// The snapshot and bucket claim APIs are optional, their resources are handled as
+// unstructured objects and skipped when the API is not installed
+var (
+	volumeSnapshotGVK      = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
+	volumeSnapshotClassGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshotClass"}
+	objectBucketClaimGVK   = schema.GroupVersionKind{Group: "objectbucket.io", Version: "v1alpha1", Kind: "ObjectBucketClaim"}
+)
+
+var blockingResourceGVKs = map[v1.BlockingResourceKind]schema.GroupVersionKind{
+	v1.BlockingPersistentVolumeClaim: corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"),
+	v1.BlockingPersistentVolume:      corev1.SchemeGroupVersion.WithKind("PersistentVolume"),
+	v1.BlockingVolumeSnapshot:        volumeSnapshotGVK,
+	v1.BlockingObjectBucketClaim:     objectBucketClaimGVK,
+}
+
+// findUninstallBlockers returns the consumer resources, in all namespaces, that still use
+// OCS storage and prevent the uninstall: PVCs and PVs with a retain policy backed by OCS,
+// VolumeSnapshots taken with an OCS driver and ObjectBucketClaims of OCS bucket classes.
+// OCS storage classes and drivers are the ones whose name is prefixed with the namespace.
+func (r *ManagedOCSReconciler) findUninstallBlockers() ([]v1.BlockingResource, error) {
+	// get all the storage classes
+	storageClassList := storagev1.StorageClassList{}
+	if err := r.UnrestrictedClient.List(r.ctx, &storageClassList); err != nil {
+		return nil, fmt.Errorf("unable to list storage classes: %v", err)
+	}
+	ocsStorageClass := make(map[string]bool)
+	for i := range storageClassList.Items {
+		storageClass := &storageClassList.Items[i]
+		if r.isOCSDriver(storageClass.Provisioner) {
+			ocsStorageClass[storageClass.Name] = true
+		}
+	}
+
+	// get all the PVs, PVCs without a storage class are resolved through their volume
+	pvList := &corev1.PersistentVolumeList{}
+	if err := r.UnrestrictedClient.List(r.ctx, pvList); err != nil {
+		return nil, fmt.Errorf("unable to list pvs: %v", err)
+	}
+	ocsPVs := map[string]*corev1.PersistentVolume{}
+	for i := range pvList.Items {
+		pv := &pvList.Items[i]
+		if ocsStorageClass[pv.Spec.StorageClassName] || (pv.Spec.CSI != nil && r.isOCSDriver(pv.Spec.CSI.Driver)) {
+			ocsPVs[pv.Name] = pv
+		}
+	}
+
+	blockers := []v1.BlockingResource{}
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := r.UnrestrictedClient.List(r.ctx, pvcList); err != nil {
+		return nil, fmt.Errorf("unable to list pvcs: %v", err)
+	}
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		scName := ""
+		if pvc.Spec.StorageClassName != nil {
+			scName = *pvc.Spec.StorageClassName
+		}
+		pv := ocsPVs[pvc.Spec.VolumeName]
+		if pv != nil && scName == "" {
+			scName = pv.Spec.StorageClassName
+		}
+		if ocsStorageClass[scName] || pv != nil {
+			blockers = append(blockers, v1.BlockingResource{
+				Kind:      v1.BlockingPersistentVolumeClaim,
+				Namespace: pvc.Namespace,
+				Name:      pvc.Name,
+				Class:     scName,
+			})
+		}
+	}
+
+	// Bound volumes are already accounted for by their claim
+	for _, pv := range ocsPVs {
+		if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain &&
+			pv.Status.Phase != corev1.VolumeBound {
+			blockers = append(blockers, v1.BlockingResource{
+				Kind:  v1.BlockingPersistentVolume,
+				Name:  pv.Name,
+				Class: pv.Spec.StorageClassName,
+			})
+		}
+	}
+
+	snapshotClasses, err := r.listUnrestricted(volumeSnapshotClassGVK)
+	if err != nil {
+		return nil, err
+	}
+	ocsSnapshotClass := map[string]bool{}
+	for i := range snapshotClasses {
+		driver, _, _ := unstructured.NestedString(snapshotClasses[i].Object, "driver")
+		if r.isOCSDriver(driver) {
+			ocsSnapshotClass[snapshotClasses[i].GetName()] = true
+		}
+	}
+	snapshots, err := r.listUnrestricted(volumeSnapshotGVK)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		className, _, _ := unstructured.NestedString(snapshots[i].Object, "spec", "volumeSnapshotClassName")
+		if ocsSnapshotClass[className] {
+			blockers = append(blockers, v1.BlockingResource{
+				Kind:      v1.BlockingVolumeSnapshot,
+				Namespace: snapshots[i].GetNamespace(),
+				Name:      snapshots[i].GetName(),
+				Class:     className,
+			})
+		}
+	}
+
+	// Bucket claims exist when MCG or the Ceph object store are enabled
+	bucketClaims, err := r.listUnrestricted(objectBucketClaimGVK)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bucketClaims {
+		scName, _, _ := unstructured.NestedString(bucketClaims[i].Object, "spec", "storageClassName")
+		if ocsStorageClass[scName] {
+			blockers = append(blockers, v1.BlockingResource{
+				Kind:      v1.BlockingObjectBucketClaim,
+				Namespace: bucketClaims[i].GetNamespace(),
+				Name:      bucketClaims[i].GetName(),
+				Class:     scName,
+			})
+		}
+	}
+
+	sort.Slice(blockers, func(i, j int) bool {
+		a, b := blockers[i], blockers[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return blockers, nil
+}
+
+func (r *ManagedOCSReconciler) isOCSDriver(driver string) bool {
+	return strings.HasPrefix(driver, r.namespace)
+}
+
+// listUnrestricted lists the resources of the given kind in all namespaces. No resources
+// are returned when the kind is not served by the cluster.
+func (r *ManagedOCSReconciler) listUnrestricted(gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.UnrestrictedClient.List(r.ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to list %s resources: %v", gvk.Kind, err)
+	}
+	return list.Items, nil
+}
+
+// countBlockingResources returns the number of blocking resources of each kind
+func countBlockingResources(blockers []v1.BlockingResource) map[v1.BlockingResourceKind]int {
+	counts := map[v1.BlockingResourceKind]int{}
+	for kind := range blockingResourceGVKs {
+		counts[kind] = 0
+	}
+	for _, blocker := range blockers {
+		counts[blocker.Kind]++
+	}
+	return counts
+}
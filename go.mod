@@ -11,6 +11,7 @@ require (
 	github.com/openshift/api v3.9.1-0.20190924102528-32369d4db2ad+incompatible
 	github.com/operator-framework/api v0.10.0
 	github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring v0.47.0
+	github.com/prometheus/client_golang v1.11.0
 	github.com/red-hat-storage/ocs-operator v0.0.1-master.0.20220204091141-8b4aa12ac5a9
 	github.com/rook/rook v1.8.3
 	go.uber.org/zap v1.19.0
@@ -21,6 +21,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"go.uber.org/zap/zapcore"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -33,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	"github.com/go-logr/logr"
 	openshiftv1 "github.com/openshift/api/network/v1"
@@ -42,6 +44,8 @@ import (
 	ocsv1 "github.com/red-hat-storage/ocs-operator/api/v1"
 	v1 "github.com/red-hat-storage/ocs-osd-deployer/api/v1alpha1"
 	"github.com/red-hat-storage/ocs-osd-deployer/controllers"
+	"github.com/red-hat-storage/ocs-osd-deployer/templates"
+	"github.com/red-hat-storage/ocs-osd-deployer/webhooks"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -51,6 +55,9 @@ const (
 	sopEndpointEnvVarName       = "SOP_ENDPOINT"
 	alertSMTPFromAddrEnvVarName = "ALERT_SMTP_FROM_ADDR"
 	deploymentTypeEnvVarName    = "DEPLOYMENT_TYPE"
+	enableWebhooksEnvVarName    = "ENABLE_WEBHOOKS"
+	clusterIDEnvVarName         = "CLUSTER_ID"
+	clusterNameEnvVarName       = "CLUSTER_NAME"
 )
 
 var (
@@ -86,14 +93,28 @@ func addAllSchemes(scheme *runtime.Scheme) {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var downscaleMaxUsageRatio float64
+	var dmsHeartbeatInterval time.Duration
+	var forceUninstallGracePeriod time.Duration
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.Float64Var(&downscaleMaxUsageRatio, "downscale-max-usage-ratio", 0.75,
+		"The maximal Ceph usage ratio, after the downscale, for which storage cluster downscaling is allowed.")
+	flag.DurationVar(&dmsHeartbeatInterval, "dms-heartbeat-interval", 5*time.Minute,
+		"The interval in which the Dead Man's Snitch heartbeat is sent.")
+	flag.DurationVar(&forceUninstallGracePeriod, "force-uninstall-grace-period", 24*time.Hour,
+		"The time between the customer warning of a force uninstall and the deletion of the resources blocking the uninstall.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.StacktraceLevel(zapcore.ErrorLevel)))
 
+	if downscaleMaxUsageRatio <= 0 || downscaleMaxUsageRatio > 1 {
+		setupLog.Error(fmt.Errorf("%v is not in the range (0, 1]", downscaleMaxUsageRatio), "Invalid downscale-max-usage-ratio flag value")
+		os.Exit(1)
+	}
+
 	envVars, err := readEnvVars()
 	if err != nil {
 		setupLog.Error(err, "Failed to get environment variables")
@@ -113,26 +134,54 @@ func main() {
 		os.Exit(1)
 	}
 
+	customerNotification, err := templates.NewCustomerNotification()
+	if err != nil {
+		setupLog.Error(err, "Invalid customer notification templates")
+		os.Exit(1)
+	}
+
 	addonName := envVars[addonNameEnvVarName]
 	if err = (&controllers.ManagedOCSReconciler{
 		Client:                       mgr.GetClient(),
 		UnrestrictedClient:           getUnrestrictedClient(),
 		Log:                          ctrl.Log.WithName("controllers").WithName("ManagedOCS"),
 		Scheme:                       mgr.GetScheme(),
+		Recorder:                     mgr.GetEventRecorderFor("managedocs-controller"),
+		AddonName:                    addonName,
+		ClusterID:                    os.Getenv(clusterIDEnvVarName),
+		ClusterName:                  os.Getenv(clusterNameEnvVarName),
 		AddonParamSecretName:         fmt.Sprintf("addon-%v-parameters", addonName),
 		AddonConfigMapName:           addonName,
 		AddonConfigMapDeleteLabelKey: fmt.Sprintf("api.openshift.com/addon-%v-delete", addonName),
 		PagerdutySecretName:          fmt.Sprintf("%v-pagerduty", addonName),
 		DeadMansSnitchSecretName:     fmt.Sprintf("%v-deadmanssnitch", addonName),
 		SMTPSecretName:               fmt.Sprintf("%v-smtp", addonName),
+		OpsgenieSecretName:           fmt.Sprintf("%v-opsgenie", addonName),
+		SlackSecretName:              fmt.Sprintf("%v-slack", addonName),
+		WebhookSecretName:            fmt.Sprintf("%v-webhook", addonName),
 		SOPEndpoint:                  envVars[sopEndpointEnvVarName],
 		AlertSMTPFrom:                envVars[alertSMTPFromAddrEnvVarName],
 		DeploymentType:               envVars[deploymentTypeEnvVarName],
-		CustomerNotificationHTMLPath: "templates/customernotification.html",
+		DownscaleMaxUsageRatio:       downscaleMaxUsageRatio,
+		DMSHeartbeatInterval:         dmsHeartbeatInterval,
+		ForceUninstallGracePeriod:    forceUninstallGracePeriod,
+		CustomerNotification:         customerNotification,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", "ManagedOCS")
 		os.Exit(1)
 	}
+	if os.Getenv(enableWebhooksEnvVarName) != "false" {
+		if err = (&v1.ManagedOCS{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "Unable to create webhook", "webhook", "ManagedOCS")
+			os.Exit(1)
+		}
+		mgr.GetWebhookServer().Register(webhooks.AddonParamsSecretValidatorPath, &webhook.Admission{
+			Handler: &webhooks.AddonParamsSecretValidator{
+				SecretName:      fmt.Sprintf("addon-%v-parameters", addonName),
+				SecretNamespace: envVars[namespaceEnvVarName],
+			},
+		})
+	}
 	// +kubebuilder:scaffold:builder
 
 	if err := ensureManagedOCS(mgr.GetClient(), setupLog, envVars); err != nil {
@@ -1,7 +1,9 @@
 // Description: This program creates a web server to verify if the managedOCS
 //              resource is ready. It is used as a readiness probe by the
-//              ocs-osd-deployer operator. For this to be set up, the following
-//              sidecar should be added to the manager CSV:
+//              ocs-osd-deployer operator. The server also lists the state of
+//              each component on /readyz?verbose and returns the full
+//              managedOCS status as JSON on /status. For this to be set up,
+//              the following sidecar should be added to the manager CSV:
 //      - name: readinessServer
 //        command:
 //        - /readinessServer
@@ -12,6 +14,12 @@
 //            port: 8081
 //          initialDelaySeconds: 5
 //          periodSeconds: 10
+//        livenessProbe:
+//          httpGet:
+//            path: /healthz
+//            port: 8081
+//          initialDelaySeconds: 5
+//          periodSeconds: 10
 package main
 
 import (
@@ -2,6 +2,8 @@ package readiness
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 
 	"github.com/go-logr/logr"
@@ -13,22 +15,58 @@ import (
 const (
 	listenAddr          string = ":8081"
 	readinessPath       string = "/readyz/"
+	healthPath          string = "/healthz"
+	statusPath          string = "/status"
 	NamespaceEnvVarName string = "NAMESPACE"
 )
 
-func isReady(client client.Client, managedOCSResource types.NamespacedName) (bool, error) {
+// componentCheck is a single component taken into account by the readiness probe
+type componentCheck struct {
+	name   string
+	status v1.ComponentStatus
+}
 
-	var managedOCS v1.ManagedOCS
+func getManagedOCS(client client.Client, managedOCSResource types.NamespacedName) (*v1.ManagedOCS, error) {
+	managedOCS := &v1.ManagedOCS{}
+	if err := client.Get(context.Background(), managedOCSResource, managedOCS); err != nil {
+		return nil, err
+	}
+	return managedOCS, nil
+}
 
-	if err := client.Get(context.Background(), managedOCSResource, &managedOCS); err != nil {
-		return false, err
+func getComponentChecks(managedOCS *v1.ManagedOCS) []componentCheck {
+	components := managedOCS.Status.Components
+	return []componentCheck{
+		{name: "storageCluster", status: components.StorageCluster},
+		{name: "prometheus", status: components.Prometheus},
+		{name: "alertmanager", status: components.Alertmanager},
 	}
+}
 
-	ready := managedOCS.Status.Components.StorageCluster.State == v1.ComponentReady &&
-		managedOCS.Status.Components.Prometheus.State == v1.ComponentReady &&
-		managedOCS.Status.Components.Alertmanager.State == v1.ComponentReady
+func isReady(managedOCS *v1.ManagedOCS) bool {
+	for _, check := range getComponentChecks(managedOCS) {
+		if check.status.State != v1.ComponentReady {
+			return false
+		}
+	}
+	return true
+}
 
-	return ready, nil
+// writeVerboseReadiness lists the state of each component, in the format of the
+// kube-apiserver verbose readyz endpoint
+func writeVerboseReadiness(httpw http.ResponseWriter, managedOCS *v1.ManagedOCS) {
+	for _, check := range getComponentChecks(managedOCS) {
+		if check.status.State == v1.ComponentReady {
+			fmt.Fprintf(httpw, "[+]%s ok\n", check.name)
+		} else {
+			fmt.Fprintf(httpw, "[-]%s failed: state is %q\n", check.name, check.status.State)
+		}
+	}
+	if isReady(managedOCS) {
+		fmt.Fprint(httpw, "readyz check passed\n")
+	} else {
+		fmt.Fprint(httpw, "readyz check failed\n")
+	}
 }
 
 func RunServer(client client.Client, managedOCSResource types.NamespacedName, log logr.Logger) error {
@@ -39,8 +77,10 @@ func RunServer(client client.Client, managedOCSResource types.NamespacedName, lo
 	// [indicates that the deployment is ready]
 	// "Any other code indicates failure."
 	// [indicates that the deployment is not ready]
+	// The component states are listed in the response body when the verbose query
+	// parameter is set.
 	http.HandleFunc(readinessPath, func(httpw http.ResponseWriter, req *http.Request) {
-		ready, err := isReady(client, managedOCSResource)
+		managedOCS, err := getManagedOCS(client, managedOCSResource)
 
 		if err != nil {
 			log.Error(err, "error checking readiness\n")
@@ -48,11 +88,42 @@ func RunServer(client client.Client, managedOCSResource types.NamespacedName, lo
 			return
 		}
 
-		if ready {
+		_, verbose := req.URL.Query()["verbose"]
+		if verbose {
+			httpw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+
+		if isReady(managedOCS) {
 			httpw.WriteHeader(http.StatusOK)
 		} else {
 			httpw.WriteHeader(http.StatusServiceUnavailable)
 		}
+
+		if verbose {
+			writeVerboseReadiness(httpw, managedOCS)
+		}
+	})
+
+	// Liveness of the readiness server itself, independent of the ManagedOCS state
+	http.HandleFunc(healthPath, func(httpw http.ResponseWriter, req *http.Request) {
+		httpw.WriteHeader(http.StatusOK)
+		fmt.Fprint(httpw, "ok")
+	})
+
+	// The full ManagedOCS status, including its conditions, for troubleshooting
+	http.HandleFunc(statusPath, func(httpw http.ResponseWriter, req *http.Request) {
+		managedOCS, err := getManagedOCS(client, managedOCSResource)
+
+		if err != nil {
+			log.Error(err, "error getting the managedocs status\n")
+			httpw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		httpw.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(httpw).Encode(managedOCS.Status); err != nil {
+			log.Error(err, "error writing the managedocs status\n")
+		}
 	})
 
 	return http.ListenAndServe(listenAddr, nil)
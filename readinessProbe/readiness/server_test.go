@@ -2,6 +2,7 @@ package readiness
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	. "github.com/onsi/ginkgo"
@@ -109,5 +110,49 @@ var _ = Describe("ManagedOCS readiness probe behavior", func() {
 			})
 		})
 
+		When("a verbose readiness check is requested", func() {
+			It("should list the state of each component", func() {
+				Expect(setupReadinessConditions(true, false, true)).Should(Succeed())
+
+				status, body, err := utils.ProbeReadinessServer("/readyz?verbose")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status).To(Equal(http.StatusServiceUnavailable))
+				Expect(string(body)).To(Equal(
+					"[+]storageCluster ok\n" +
+						"[-]prometheus failed: state is \"Pending\"\n" +
+						"[+]alertmanager ok\n" +
+						"readyz check failed\n",
+				))
+			})
+		})
+
+	})
+
+	Context("Health and status endpoints", func() {
+		When("the health of the readiness server is checked", func() {
+			It("should return StatusOK regardless of the managedocs state", func() {
+				Expect(setupReadinessConditions(false, false, false)).Should(Succeed())
+
+				status, body, err := utils.ProbeReadinessServer("/healthz")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status).To(Equal(http.StatusOK))
+				Expect(string(body)).To(Equal("ok"))
+			})
+		})
+
+		When("the managedocs status is requested", func() {
+			It("should return the managedocs status as JSON", func() {
+				Expect(setupReadinessConditions(true, true, false)).Should(Succeed())
+
+				status, body, err := utils.ProbeReadinessServer("/status")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status).To(Equal(http.StatusOK))
+
+				var managedOCSStatus v1.ManagedOCSStatus
+				Expect(json.Unmarshal(body, &managedOCSStatus)).Should(Succeed())
+				Expect(managedOCSStatus.Components.StorageCluster.State).To(Equal(v1.ComponentReady))
+				Expect(managedOCSStatus.Components.Alertmanager.State).To(Equal(v1.ComponentPending))
+			})
+		})
 	})
 })
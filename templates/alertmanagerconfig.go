@@ -14,6 +14,7 @@ package templates
 
 import (
 	"encoding/json"
+	"fmt"
 
 	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
 	"github.com/red-hat-storage/ocs-osd-deployer/utils"
@@ -33,100 +34,212 @@ func convertToApiExtV1JSON(val interface{}) apiextensionsv1.JSON {
 }
 
 var _false = false
+var _true = true
 
-var pagerdutyAlerts = []string{
-	"CephMdsMissingReplicas",
-	"CephMgrIsAbsent",
-	"CephMgrIsMissingReplicas",
-	"CephNodeDown",
-	"CephClusterErrorState",
-	"CephClusterWarningState",
-	"CephOSDVersionMismatch",
-	"CephMonVersionMismatch",
-	"CephOSDFlapping",
-	"CephOSDDiskNotResponding",
-	"CephOSDDiskUnavailable",
-	"CephDataRecoveryTakingTooLong",
-	"CephPGRepairTakingTooLong",
-	"CephMonQuorumAtRisk",
-	"CephMonHighNumberOfLeaderChanges",
+// pagerdutySeverity maps the severity label of the alerts to an Events API v2 severity
+const pagerdutySeverity = `{{ if eq .CommonLabels.severity "critical" }}critical` +
+	`{{ else if eq .CommonLabels.severity "warning" }}warning` +
+	`{{ else if eq .CommonLabels.severity "info" }}info` +
+	`{{ else }}error{{ end }}`
+
+// PVCNamespaceLabelKey is the alert label holding the namespace of the PVC the volume usage
+// alerts refer to, as the namespace label is overwritten with the deployer namespace
+const PVCNamespaceLabelKey = "pvc_namespace"
+
+// Severities of the customer alerts that are batched into the daily digest email
+var customerNotificationDigestSeverities = []string{"warning", "info"}
+
+// customerNotificationDigestInterval is the time between two customer notification digests
+const customerNotificationDigestInterval = "24h"
+
+// inhibitRules mute alerts that are redundant while a related, more severe, alert is firing
+var inhibitRules = []promv1a1.InhibitRule{
+	newInhibitRule("CephClusterErrorState", []string{"CephClusterWarningState"}, "namespace"),
+	newInhibitRule("CephMonQuorumAtRisk", []string{"CephClusterWarningState"}, "namespace"),
+	newInhibitRule("CephNodeDown", []string{"CephClusterWarningState"}, "namespace"),
+	newInhibitRule("CephNodeDown", []string{
+		"CephOSDDiskUnavailable",
+		"CephOSDDiskNotResponding",
+		"CephOSDFlapping",
+	}, "namespace", "node"),
+	newInhibitRule("CephClusterReadOnly", []string{"CephClusterCriticallyFull", "CephClusterNearFull"}, "namespace"),
+	newInhibitRule("CephClusterCriticallyFull", []string{"CephClusterNearFull"}, "namespace"),
+	newInhibitRule("PersistentVolumeUsageCritical", []string{"PersistentVolumeUsageNearFull"}, PVCNamespaceLabelKey, "persistentvolumeclaim"),
 }
-var smtpAlerts = []string{
-	"CephClusterNearFull",
-	"CephClusterCriticallyFull",
-	"CephClusterReadOnly",
-	"PersistentVolumeUsageNearFull",
-	"PersistentVolumeUsageCritical",
+
+func newInhibitRule(sourceAlert string, targetAlerts []string, equal ...string) promv1a1.InhibitRule {
+	return promv1a1.InhibitRule{
+		SourceMatch: []promv1a1.Matcher{{Name: "alertname", Value: sourceAlert}},
+		TargetMatch: []promv1a1.Matcher{{Name: "alertname", Value: utils.GetRegexMatcher(targetAlerts), Regex: true}},
+		Equal:       equal,
+	}
 }
 
-// List of silenced alerts
-//
-// OSD Full alerts are silenced as there is no scenario in our static deployment
-// configuration where an OSD is getting full without the cluster getting full.
-// CephOSDCriticallyFull
-// CephOSDNearFull
-
-var AlertmanagerConfigTemplate = promv1a1.AlertmanagerConfig{
-	Spec: promv1a1.AlertmanagerConfigSpec{
-		Route: &promv1a1.Route{
-			Receiver: "null",
-			Routes: []apiextensionsv1.JSON{
-				convertToApiExtV1JSON(promv1a1.Route{
-					GroupBy:        []string{"alertname"},
-					GroupWait:      "30s",
-					GroupInterval:  "5m",
-					RepeatInterval: "12h",
-					Matchers:       []promv1a1.Matcher{{Name: "alertname", Value: utils.GetRegexMatcher(smtpAlerts), Regex: true}},
-					Receiver:       "SendGrid",
-				},
-				),
-				convertToApiExtV1JSON(promv1a1.Route{
-					GroupBy:        []string{"alertname"},
-					GroupWait:      "30s",
-					GroupInterval:  "5m",
-					RepeatInterval: "12h",
-					Matchers:       []promv1a1.Matcher{{Name: "alertname", Value: utils.GetRegexMatcher(pagerdutyAlerts), Regex: true}},
-					Receiver:       "pagerduty",
+// AlertmanagerConfigTemplate is the alert routing used by deployments that run
+// a local Ceph cluster (converged and provider)
+var AlertmanagerConfigTemplate = NewAlertmanagerConfig(&DefaultAlertRoutingPolicy)
+
+// NewAlertmanagerConfig renders an alertmanager config with the routes described by the given
+// routing policy. The DeadMansSnitch routes come first so the heartbeat is never silenced,
+// followed by the silenced alerts, routed to the null receiver. Alerts that do not match any
+// of the routes are dropped as well.
+func NewAlertmanagerConfig(policy *AlertRoutingPolicy) promv1a1.AlertmanagerConfig {
+	dmsRoutes := []apiextensionsv1.JSON{}
+	otherRoutes := []apiextensionsv1.JSON{}
+	for i := range policy.Routes {
+		rule := &policy.Routes[i]
+		if len(rule.Alerts) == 0 {
+			continue
+		}
+		route := convertToApiExtV1JSON(promv1a1.Route{
+			GroupBy:        []string{"alertname"},
+			GroupWait:      rule.GroupWait,
+			GroupInterval:  rule.GroupInterval,
+			RepeatInterval: rule.RepeatInterval,
+			Matchers:       []promv1a1.Matcher{{Name: "alertname", Value: utils.GetRegexMatcher(rule.Alerts), Regex: true}},
+			Receiver:       rule.Receiver,
+			Continue:       rule.Continue,
+		})
+		if rule.Receiver == DeadMansSnitchReceiverName {
+			dmsRoutes = append(dmsRoutes, route)
+		} else {
+			otherRoutes = append(otherRoutes, route)
+		}
+	}
+
+	routes := dmsRoutes
+	if len(policy.Silences) > 0 {
+		routes = append(routes, convertToApiExtV1JSON(promv1a1.Route{
+			Matchers: []promv1a1.Matcher{{Name: "alertname", Value: utils.GetRegexMatcher(policy.Silences), Regex: true}},
+			Receiver: nullReceiverName,
+		}))
+	}
+	routes = append(routes, otherRoutes...)
+
+	return promv1a1.AlertmanagerConfig{
+		Spec: promv1a1.AlertmanagerConfigSpec{
+			Route: &promv1a1.Route{
+				Receiver: nullReceiverName,
+				Routes:   routes,
+			},
+			InhibitRules: inhibitRules,
+			Receivers: []promv1a1.Receiver{{
+				Name: nullReceiverName,
+			}, {
+				Name: PagerdutyReceiverName,
+				PagerDutyConfigs: []promv1a1.PagerDutyConfig{{
+					ServiceKey: &corev1.SecretKeySelector{Key: "", LocalObjectReference: corev1.LocalObjectReference{Name: ""}},
+					Severity:   pagerdutySeverity,
+					Details:    []promv1a1.KeyValue{{Key: "", Value: ""}},
+				}},
+			}, {
+				Name:           DeadMansSnitchReceiverName,
+				WebhookConfigs: []promv1a1.WebhookConfig{{}},
+			}, {
+				Name: SendGridReceiverName,
+				EmailConfigs: []promv1a1.EmailConfig{{
+					SendResolved: &_false,
+					Smarthost:    "",
+					From:         "",
+					To:           "",
+					AuthUsername: "",
+					AuthPassword: &corev1.SecretKeySelector{Key: "", LocalObjectReference: corev1.LocalObjectReference{Name: ""}},
+					Headers: []promv1a1.KeyValue{{
+						Key:   "subject",
+						Value: `OpenShift Data Foundation Managed Service notification, Action required on your managed OpenShift cluster!`,
+					}},
 				},
-				),
-				convertToApiExtV1JSON(promv1a1.Route{
-					GroupBy:        []string{"alertname"},
-					GroupWait:      "30s",
-					GroupInterval:  "5m",
-					RepeatInterval: "5m",
-					Matchers:       []promv1a1.Matcher{{Name: "alertname", Value: "DeadMansSnitch"}},
-					Receiver:       "DeadMansSnitch",
 				},
-				),
-			},
-		},
-		Receivers: []promv1a1.Receiver{{
-			Name: "null",
-		}, {
-			Name: "pagerduty",
-			PagerDutyConfigs: []promv1a1.PagerDutyConfig{{
-				ServiceKey: &corev1.SecretKeySelector{Key: "", LocalObjectReference: corev1.LocalObjectReference{Name: ""}},
-				Details:    []promv1a1.KeyValue{{Key: "", Value: ""}},
-			}},
-		}, {
-			Name:           "DeadMansSnitch",
-			WebhookConfigs: []promv1a1.WebhookConfig{{}},
-		}, {
-			Name: "SendGrid",
-			EmailConfigs: []promv1a1.EmailConfig{{
-				SendResolved: &_false,
-				Smarthost:    "",
-				From:         "",
-				To:           "",
-				AuthUsername: "",
-				AuthPassword: &corev1.SecretKeySelector{Key: "", LocalObjectReference: corev1.LocalObjectReference{Name: ""}},
-				Headers: []promv1a1.KeyValue{{
-					Key:   "subject",
-					Value: `OpenShift Data Foundation Managed Service notification, Action required on your managed OpenShift cluster!`,
+			}, {
+				Name: SendGridDigestReceiverName,
+				EmailConfigs: []promv1a1.EmailConfig{{
+					SendResolved: &_false,
+					AuthPassword: &corev1.SecretKeySelector{},
+					Headers:      []promv1a1.KeyValue{{Key: "subject"}},
+				}},
+			}, {
+				Name: OpsgenieReceiverName,
+				OpsGenieConfigs: []promv1a1.OpsGenieConfig{{
+					SendResolved: &_true,
+					APIKey:       &corev1.SecretKeySelector{},
+				}},
+			}, {
+				Name: SlackReceiverName,
+				SlackConfigs: []promv1a1.SlackConfig{{
+					SendResolved: &_true,
+					APIURL:       &corev1.SecretKeySelector{},
+				}},
+			}, {
+				Name: WebhookReceiverName,
+				WebhookConfigs: []promv1a1.WebhookConfig{{
+					SendResolved: &_true,
+					URLSecret:    &corev1.SecretKeySelector{},
 				}},
 			},
 			},
 		},
-		},
-	},
+	}
+}
+
+// EnableCustomerNotificationDigest batches the non-critical alerts sent to the customer into a
+// daily digest. A digest route is derived from each SendGrid route of the given config, and is
+// inserted right before it so it takes precedence for the matching severities.
+func EnableCustomerNotificationDigest(config *promv1a1.AlertmanagerConfig) error {
+	routes := []apiextensionsv1.JSON{}
+	for _, raw := range config.Spec.Route.Routes {
+		route := promv1a1.Route{}
+		if err := json.Unmarshal(raw.Raw, &route); err != nil {
+			return fmt.Errorf("Failed to parse alertmanager config route: %v", err)
+		}
+		if route.Receiver == SendGridReceiverName {
+			digestRoute := route.DeepCopy()
+			digestRoute.Receiver = SendGridDigestReceiverName
+			digestRoute.Matchers = append(digestRoute.Matchers, promv1a1.Matcher{
+				Name:  "severity",
+				Value: utils.GetRegexMatcher(customerNotificationDigestSeverities),
+				Regex: true,
+			})
+			// Alerts are held for a day before the first digest is sent, then batched daily
+			digestRoute.GroupBy = []string{"severity"}
+			digestRoute.GroupWait = customerNotificationDigestInterval
+			digestRoute.GroupInterval = customerNotificationDigestInterval
+			digestRoute.RepeatInterval = customerNotificationDigestInterval
+			routes = append(routes, convertToApiExtV1JSON(digestRoute))
+		}
+		routes = append(routes, raw)
+	}
+	config.Spec.Route.Routes = routes
+	return nil
+}
+
+// SilenceAlerts routes the alerts matching any of the given matcher sets to the null receiver.
+// The DeadMansSnitch routes are moved first, so the heartbeat is never silenced.
+func SilenceAlerts(config *promv1a1.AlertmanagerConfig, matcherSets [][]promv1a1.Matcher) error {
+	if len(matcherSets) == 0 {
+		return nil
+	}
+
+	dmsRoutes := []apiextensionsv1.JSON{}
+	otherRoutes := []apiextensionsv1.JSON{}
+	for _, raw := range config.Spec.Route.Routes {
+		route := promv1a1.Route{}
+		if err := json.Unmarshal(raw.Raw, &route); err != nil {
+			return fmt.Errorf("Failed to parse alertmanager config route: %v", err)
+		}
+		if route.Receiver == DeadMansSnitchReceiverName {
+			dmsRoutes = append(dmsRoutes, raw)
+		} else {
+			otherRoutes = append(otherRoutes, raw)
+		}
+	}
+
+	routes := dmsRoutes
+	for _, matchers := range matcherSets {
+		routes = append(routes, convertToApiExtV1JSON(promv1a1.Route{
+			Matchers: matchers,
+			Receiver: nullReceiverName,
+		}))
+	}
+	config.Spec.Route.Routes = append(routes, otherRoutes...)
+	return nil
 }
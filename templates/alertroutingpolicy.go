@@ -0,0 +1,299 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Names of the alertmanager config receivers
+const (
+	nullReceiverName           = "null"
+	PagerdutyReceiverName      = "pagerduty"
+	DeadMansSnitchReceiverName = "DeadMansSnitch"
+	SendGridReceiverName       = "SendGrid"
+	OpsgenieReceiverName       = "opsgenie"
+	SlackReceiverName          = "slack"
+	WebhookReceiverName        = "webhook"
+
+	// SendGridDigestReceiverName is not routable from the policy, alerts are routed to
+	// it when the customer notification digest is enabled
+	SendGridDigestReceiverName = "SendGridDigest"
+)
+
+// DeadMansSnitchAlertName is the heartbeat alert, which must never be silenced
+const DeadMansSnitchAlertName = "DeadMansSnitch"
+
+// durationRegex matches the duration format accepted by alertmanager
+var durationRegex = regexp.MustCompile(`^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`)
+
+// routableReceivers are the receivers alert routing rules can point to
+var routableReceivers = []string{
+	PagerdutyReceiverName,
+	DeadMansSnitchReceiverName,
+	SendGridReceiverName,
+	OpsgenieReceiverName,
+	SlackReceiverName,
+	WebhookReceiverName,
+}
+
+// AlertRoutingPolicy describes how alerts are routed to the alertmanager receivers
+type AlertRoutingPolicy struct {
+	// Routes are evaluated in order, an alert is sent to the receiver of the first matching route
+	Routes []AlertRoutingRule `yaml:"routes,omitempty"`
+
+	// Silences are alert name patterns that are never sent to any receiver
+	Silences []string `yaml:"silences,omitempty"`
+}
+
+// AlertRoutingRule routes alerts whose name match any of the given patterns to a receiver
+type AlertRoutingRule struct {
+	Receiver       string   `yaml:"receiver"`
+	Alerts         []string `yaml:"alerts,omitempty"`
+	GroupWait      string   `yaml:"groupWait,omitempty"`
+	GroupInterval  string   `yaml:"groupInterval,omitempty"`
+	RepeatInterval string   `yaml:"repeatInterval,omitempty"`
+
+	// Continue indicates that alerts matching the rule should also be matched
+	// against the following rules
+	Continue bool `yaml:"continue,omitempty"`
+}
+
+// Alerts that are sent to the customer
+var customerAlerts = []string{
+	"CephClusterNearFull",
+	"CephClusterCriticallyFull",
+	"CephClusterReadOnly",
+	"PersistentVolumeUsageNearFull",
+	"PersistentVolumeUsageCritical",
+	ForceUninstallScheduledAlert,
+}
+
+// Alerts that require SRE intervention
+var sreAlerts = []string{
+	"CephMdsMissingReplicas",
+	"CephMgrIsAbsent",
+	"CephMgrIsMissingReplicas",
+	"CephNodeDown",
+	"CephClusterErrorState",
+	"CephClusterWarningState",
+	"CephOSDVersionMismatch",
+	"CephMonVersionMismatch",
+	"CephOSDFlapping",
+	"CephOSDDiskNotResponding",
+	"CephOSDDiskUnavailable",
+	"CephDataRecoveryTakingTooLong",
+	"CephPGRepairTakingTooLong",
+	"CephMonQuorumAtRisk",
+	"CephMonHighNumberOfLeaderChanges",
+}
+
+// Consumer clusters do not run Ceph locally, the Ceph alerts are raised and routed
+// on the provider cluster. Only the volume usage alerts are relevant to the consumer.
+var consumerCustomerAlerts = []string{
+	"PersistentVolumeUsageNearFull",
+	"PersistentVolumeUsageCritical",
+	ForceUninstallScheduledAlert,
+}
+
+// DefaultAlertRoutingPolicy is the built-in routing of deployments that run a local Ceph
+// cluster (converged and provider). The optional receivers come first and let alerts
+// continue to the main receivers.
+var DefaultAlertRoutingPolicy = AlertRoutingPolicy{
+	Routes: []AlertRoutingRule{
+		newOptionalAlertRoutingRule(OpsgenieReceiverName, sreAlerts),
+		newOptionalAlertRoutingRule(SlackReceiverName, append(append([]string{}, sreAlerts...), customerAlerts...)),
+		newOptionalAlertRoutingRule(WebhookReceiverName, append(append([]string{}, sreAlerts...), customerAlerts...)),
+		{
+			Receiver:       SendGridReceiverName,
+			Alerts:         customerAlerts,
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "12h",
+		}, {
+			Receiver:       PagerdutyReceiverName,
+			Alerts:         sreAlerts,
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "12h",
+		},
+		dmsAlertRoutingRule,
+	},
+	// OSD Full alerts are silenced as there is no scenario in our static deployment
+	// configuration where an OSD is getting full without the cluster getting full.
+	Silences: []string{
+		"CephOSDCriticallyFull",
+		"CephOSDNearFull",
+	},
+}
+
+// ConsumerAlertRoutingPolicy is the built-in routing of consumer deployments
+var ConsumerAlertRoutingPolicy = AlertRoutingPolicy{
+	Routes: []AlertRoutingRule{
+		newOptionalAlertRoutingRule(SlackReceiverName, consumerCustomerAlerts),
+		newOptionalAlertRoutingRule(WebhookReceiverName, consumerCustomerAlerts),
+		{
+			Receiver:       SendGridReceiverName,
+			Alerts:         consumerCustomerAlerts,
+			GroupWait:      "30s",
+			GroupInterval:  "5m",
+			RepeatInterval: "12h",
+		},
+		dmsAlertRoutingRule,
+	},
+}
+
+func newOptionalAlertRoutingRule(receiver string, alerts []string) AlertRoutingRule {
+	return AlertRoutingRule{
+		Receiver:       receiver,
+		Alerts:         alerts,
+		GroupWait:      "30s",
+		GroupInterval:  "5m",
+		RepeatInterval: "12h",
+		Continue:       true,
+	}
+}
+
+var dmsAlertRoutingRule = AlertRoutingRule{
+	Receiver:       DeadMansSnitchReceiverName,
+	Alerts:         []string{DeadMansSnitchAlertName},
+	GroupWait:      "30s",
+	GroupInterval:  "5m",
+	RepeatInterval: "5m",
+}
+
+// Merge returns a copy of the policy with the given overrides applied. A rule in the
+// overrides replaces the non empty fields of the rule with the same receiver, or is
+// appended if no such rule exists. A non nil silence list replaces the silence list.
+func (p *AlertRoutingPolicy) Merge(overrides *AlertRoutingPolicy) *AlertRoutingPolicy {
+	merged := p.DeepCopy()
+	for _, override := range overrides.Routes {
+		var rule *AlertRoutingRule = nil
+		for i := range merged.Routes {
+			if merged.Routes[i].Receiver == override.Receiver {
+				rule = &merged.Routes[i]
+				break
+			}
+		}
+		if rule == nil {
+			merged.Routes = append(merged.Routes, AlertRoutingRule{Receiver: override.Receiver, Continue: override.Continue})
+			rule = &merged.Routes[len(merged.Routes)-1]
+		}
+		if override.Alerts != nil {
+			rule.Alerts = append([]string{}, override.Alerts...)
+		}
+		if override.GroupWait != "" {
+			rule.GroupWait = override.GroupWait
+		}
+		if override.GroupInterval != "" {
+			rule.GroupInterval = override.GroupInterval
+		}
+		if override.RepeatInterval != "" {
+			rule.RepeatInterval = override.RepeatInterval
+		}
+	}
+	if overrides.Silences != nil {
+		merged.Silences = append([]string{}, overrides.Silences...)
+	}
+	return merged
+}
+
+// WithoutReceivers returns a copy of the policy without the rules that route alerts to
+// any of the given receivers
+func (p *AlertRoutingPolicy) WithoutReceivers(receivers []string) *AlertRoutingPolicy {
+	filtered := p.DeepCopy()
+	filtered.Routes = []AlertRoutingRule{}
+	for _, rule := range p.DeepCopy().Routes {
+		if !utils.Contains(receivers, rule.Receiver) {
+			filtered.Routes = append(filtered.Routes, rule)
+		}
+	}
+	return filtered
+}
+
+// Validate checks that the routes point to known receivers, and that alert name
+// patterns and timings can be parsed by alertmanager
+func (p *AlertRoutingPolicy) Validate() error {
+	errs := []error{}
+	for i := range p.Routes {
+		rule := &p.Routes[i]
+		if !utils.Contains(routableReceivers, rule.Receiver) {
+			errs = append(errs, fmt.Errorf("routes[%d]: unknown receiver %q", i, rule.Receiver))
+		}
+		for _, pattern := range rule.Alerts {
+			if _, err := regexp.Compile(pattern); err != nil {
+				errs = append(errs, fmt.Errorf("routes[%d]: invalid alert name pattern %q: %v", i, pattern, err))
+			}
+		}
+		timings := []struct{ name, value string }{
+			{"groupWait", rule.GroupWait},
+			{"groupInterval", rule.GroupInterval},
+			{"repeatInterval", rule.RepeatInterval},
+		}
+		for _, timing := range timings {
+			if timing.value != "" && !durationRegex.MatchString(timing.value) {
+				errs = append(errs, fmt.Errorf("routes[%d]: invalid %s value %q", i, timing.name, timing.value))
+			}
+		}
+	}
+	if !p.routesDeadMansSnitchAlert() {
+		errs = append(errs, fmt.Errorf("routes: no %s rule matches the %s alert", DeadMansSnitchReceiverName, DeadMansSnitchAlertName))
+	}
+	for _, pattern := range p.Silences {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("silences: invalid alert name pattern %q: %v", pattern, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// routesDeadMansSnitchAlert returns true when a rule routes the heartbeat alert to the
+// DeadMansSnitch receiver
+func (p *AlertRoutingPolicy) routesDeadMansSnitchAlert() bool {
+	for i := range p.Routes {
+		rule := &p.Routes[i]
+		if rule.Receiver != DeadMansSnitchReceiverName {
+			continue
+		}
+		for _, pattern := range rule.Alerts {
+			if matched, _ := regexp.MatchString("^(?:"+pattern+")$", DeadMansSnitchAlertName); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DeepCopy returns a deep copy of the policy
+func (p *AlertRoutingPolicy) DeepCopy() *AlertRoutingPolicy {
+	out := &AlertRoutingPolicy{}
+	if p.Routes != nil {
+		out.Routes = make([]AlertRoutingRule, len(p.Routes))
+		for i := range p.Routes {
+			out.Routes[i] = p.Routes[i]
+			out.Routes[i].Alerts = append([]string(nil), p.Routes[i].Alerts...)
+		}
+	}
+	if p.Silences != nil {
+		out.Silences = append([]string{}, p.Silences...)
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+)
+
+// The customer notification templates are rendered in two stages. The operator renders
+// the files below, delimited with [[ ]], into alertmanager templates. Alertmanager then
+// renders the {{ }} actions, with its template variables, when the email is sent.
+//
+//go:embed customernotification
+var customerNotificationFS embed.FS
+
+const (
+	customerNotificationDir         = "customernotification"
+	customerNotificationAlertsDir   = "customernotification/alerts"
+	customerNotificationHTMLFile    = "email.html.tmpl"
+	customerNotificationSubjectFile = "email.subject.tmpl"
+
+	defaultCustomerNotificationSubject = "OpenShift Data Foundation Managed Service notification, Action required on your managed OpenShift cluster!"
+	customerNotificationDigestSubject  = "OpenShift Data Foundation Managed Service daily digest of your managed OpenShift cluster"
+)
+
+// alertmanagerTemplateFuncs stubs the functions alertmanager makes available to its
+// templates, so the rendered templates can be parsed the way alertmanager does
+var alertmanagerTemplateFuncs = template.FuncMap{
+	"toUpper":      strings.ToUpper,
+	"toLower":      strings.ToLower,
+	"title":        strings.Title,
+	"join":         strings.Join,
+	"match":        func(string, string) bool { return false },
+	"safeHtml":     func(text string) string { return text },
+	"reReplaceAll": func(string, string, string) string { return "" },
+	"stringSlice":  func(s ...string) []string { return s },
+}
+
+// CustomerNotification is the content of the email sent to the customer, as alertmanager
+// templates. Both the subject and the HTML body depend on the name of the alert. The digest
+// email batches several alerts and uses a fixed subject.
+type CustomerNotification struct {
+	Subject       string
+	DigestSubject string
+	HTML          string
+}
+
+// alertNotification is the customer facing content of a single alert
+type alertNotification struct {
+	AlertName   string
+	Subject     string
+	Body        string
+	Remediation string
+	Resolved    string
+}
+
+// NewCustomerNotification renders the embedded customer notification templates. An error is
+// returned when a template is malformed, or when one of the alerts routed to the customer by
+// the built-in policies does not have a template.
+func NewCustomerNotification() (*CustomerNotification, error) {
+	alerts, err := getAlertNotifications()
+	if err != nil {
+		return nil, err
+	}
+
+	alertNames := []string{}
+	for _, alert := range alerts {
+		alertNames = append(alertNames, alert.AlertName)
+	}
+	for _, policy := range []*AlertRoutingPolicy{&DefaultAlertRoutingPolicy, &ConsumerAlertRoutingPolicy} {
+		for _, rule := range policy.Routes {
+			if rule.Receiver != SendGridReceiverName {
+				continue
+			}
+			for _, alertName := range rule.Alerts {
+				if !utils.Contains(alertNames, alertName) {
+					return nil, fmt.Errorf("Missing customer notification template for alert %v", alertName)
+				}
+			}
+		}
+	}
+
+	data := struct {
+		Alerts         []alertNotification
+		DefaultSubject string
+	}{alerts, defaultCustomerNotificationSubject}
+
+	notification := &CustomerNotification{DigestSubject: customerNotificationDigestSubject}
+	if notification.Subject, err = renderCustomerNotificationFile(customerNotificationSubjectFile, data); err != nil {
+		return nil, err
+	}
+	notification.Subject = strings.TrimSpace(notification.Subject)
+	if notification.HTML, err = renderCustomerNotificationFile(customerNotificationHTMLFile, data); err != nil {
+		return nil, err
+	}
+	return notification, nil
+}
+
+// getAlertNotifications renders the per alert templates, named after the alert they describe
+func getAlertNotifications() ([]alertNotification, error) {
+	entries, err := customerNotificationFS.ReadDir(customerNotificationAlertsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := []alertNotification{}
+	for _, entry := range entries {
+		fileName := path.Join(customerNotificationAlertsDir, entry.Name())
+		tmpl, err := newCustomerNotificationTemplate(entry.Name()).ParseFS(customerNotificationFS, fileName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse %v: %v", fileName, err)
+		}
+
+		alert := alertNotification{AlertName: strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))}
+		for name, value := range map[string]*string{
+			"subject":     &alert.Subject,
+			"body":        &alert.Body,
+			"remediation": &alert.Remediation,
+			"resolved":    &alert.Resolved,
+		} {
+			out, err := executeCustomerNotificationTemplate(tmpl, name, nil)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to render %v: %v", fileName, err)
+			}
+			if out == "" {
+				return nil, fmt.Errorf("Template %v does not define a %v", fileName, name)
+			}
+			*value = out
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// renderCustomerNotificationFile renders one of the email templates and checks that the
+// output is a valid alertmanager template
+func renderCustomerNotificationFile(name string, data interface{}) (string, error) {
+	tmpl, err := newCustomerNotificationTemplate(name).ParseFS(customerNotificationFS, path.Join(customerNotificationDir, name))
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse %v: %v", name, err)
+	}
+	out, err := executeCustomerNotificationTemplate(tmpl, name, data)
+	if err != nil {
+		return "", fmt.Errorf("Failed to render %v: %v", name, err)
+	}
+	if _, err := template.New(name).Funcs(alertmanagerTemplateFuncs).Parse(out); err != nil {
+		return "", fmt.Errorf("Rendered %v is not a valid alertmanager template: %v", name, err)
+	}
+	return out, nil
+}
+
+func newCustomerNotificationTemplate(name string) *template.Template {
+	return template.New(name).Delims("[[", "]]").Option("missingkey=error")
+}
+
+func executeCustomerNotificationTemplate(tmpl *template.Template, name string, data interface{}) (string, error) {
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
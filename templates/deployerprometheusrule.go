@@ -0,0 +1,53 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// ForceUninstallScheduledAlert warns the customer ahead of the deletion of the resources
+// that block a forced uninstall
+const ForceUninstallScheduledAlert = "ManagedOCSForceUninstallScheduled"
+
+// DeployerPrometheusRuleTemplate holds the alerts raised on the deployer metrics
+var DeployerPrometheusRuleTemplate = promv1.PrometheusRule{
+	Spec: promv1.PrometheusRuleSpec{
+		Groups: []promv1.RuleGroup{
+			{
+				Name: "ocs-osd-deployer.rules",
+				Rules: []promv1.Rule{
+					{
+						Alert: ForceUninstallScheduledAlert,
+						Expr: intstr.IntOrString{
+							Type:   intstr.String,
+							StrVal: "managedocs_force_uninstall_deletion_timestamp_seconds > 0",
+						},
+						// Critical, the warning must not be held back by the daily customer notification digest
+						Labels: map[string]string{
+							"severity": "critical",
+						},
+						Annotations: map[string]string{
+							"deletion_time": "{{ $value | humanizeTimestamp }}",
+						},
+					},
+				},
+			},
+		},
+	},
+}
@@ -0,0 +1,148 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var osdRemovalJobBackoffLimit int32 = 0
+
+// OSDJobIDsEnvVarName is the environment variable that holds the comma separated IDs of the
+// OSDs handled by an OSD job, it is set when the job is created
+const OSDJobIDsEnvVarName = "OSD_IDS"
+
+// osdOutScript marks OSDs out, with a Ceph client configuration generated from the rook mon
+// endpoints and credentials, as done by the rook toolbox
+const osdOutScript = `set -e
+mon_host=$(echo "${ROOK_MON_ENDPOINTS}" | sed 's/[a-z0-9_-]\+=//g')
+cat <<EOF > /etc/ceph/ceph.conf
+[global]
+mon_host = ${mon_host}
+
+[${ROOK_CEPH_USERNAME}]
+keyring = /etc/ceph/keyring
+EOF
+cat <<EOF > /etc/ceph/keyring
+[${ROOK_CEPH_USERNAME}]
+key = ${ROOK_CEPH_SECRET}
+EOF
+ceph --name "${ROOK_CEPH_USERNAME}" osd out ${OSD_IDS//,/ }
+`
+
+// OSDRemovalJobTemplate is the job that purges OSDs from the Ceph cluster, modeled after
+// the ocs-osd-removal template of ocs-operator. The rook image and the OSD IDs are set
+// when the job is created.
+var OSDRemovalJobTemplate = batchv1.Job{
+	Spec: batchv1.JobSpec{
+		BackoffLimit: &osdRemovalJobBackoffLimit,
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "rook-ceph-system",
+				RestartPolicy:      corev1.RestartPolicyNever,
+				Containers: []corev1.Container{
+					{
+						Name: "operator",
+						Args: []string{"ceph", "osd", "remove", "--osd-ids=$(" + OSDJobIDsEnvVarName + ")"},
+						Env: []corev1.EnvVar{
+							{
+								Name: "POD_NAMESPACE",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+								},
+							},
+							{
+								Name: "ROOK_MON_ENDPOINTS",
+								ValueFrom: &corev1.EnvVarSource{
+									ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "rook-ceph-mon-endpoints"},
+										Key:                  "data",
+									},
+								},
+							},
+							{
+								Name: "ROOK_CEPH_USERNAME",
+								ValueFrom: &corev1.EnvVarSource{
+									SecretKeyRef: &corev1.SecretKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "rook-ceph-mon"},
+										Key:                  "ceph-username",
+									},
+								},
+							},
+							{
+								Name: "ROOK_CEPH_SECRET",
+								ValueFrom: &corev1.EnvVarSource{
+									SecretKeyRef: &corev1.SecretKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "rook-ceph-mon"},
+										Key:                  "ceph-secret",
+									},
+								},
+							},
+							{
+								Name: "ROOK_FSID",
+								ValueFrom: &corev1.EnvVarSource{
+									SecretKeyRef: &corev1.SecretKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "rook-ceph-mon"},
+										Key:                  "fsid",
+									},
+								},
+							},
+							{
+								Name:  "ROOK_CONFIG_DIR",
+								Value: "/var/lib/rook",
+							},
+							{
+								Name:  "ROOK_CEPH_CONFIG_OVERRIDE",
+								Value: "/etc/rook/config/override.conf",
+							},
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "ceph-conf-emptydir",
+								MountPath: "/etc/ceph",
+							},
+							{
+								Name:      "rook-config",
+								MountPath: "/var/lib/rook",
+							},
+						},
+					},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name:         "ceph-conf-emptydir",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					},
+					{
+						Name:         "rook-config",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					},
+				},
+			},
+		},
+	},
+}
+
+// OSDOutJobTemplate is the job that marks OSDs out, so that Ceph moves their data to the
+// other OSDs. It runs with the same image and Ceph credentials as the OSD removal job.
+var OSDOutJobTemplate = newOSDOutJobTemplate()
+
+func newOSDOutJobTemplate() batchv1.Job {
+	job := *OSDRemovalJobTemplate.DeepCopy()
+	container := &job.Spec.Template.Spec.Containers[0]
+	container.Name = "ceph"
+	container.Command = []string{"/bin/bash", "-c", osdOutScript}
+	container.Args = nil
+	return job
+}
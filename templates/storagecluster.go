@@ -84,3 +84,14 @@ var StorageClusterTemplate = ocsv1.StorageCluster{
 		},
 	},
 }
+
+// ConsumerStorageClusterTemplate is the template for consumer deployments, the storage
+// cluster runs in external mode and consumes storage exposed by a provider cluster
+var ConsumerStorageClusterTemplate = ocsv1.StorageCluster{
+	Spec: ocsv1.StorageClusterSpec{
+		ExternalStorage: ocsv1.ExternalStorageClusterSpec{
+			Enable:              true,
+			StorageProviderKind: ocsv1.KindOCS,
+		},
+	},
+}
@@ -3,6 +3,7 @@ package testutils
 import (
 	"context"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 
 	. "github.com/onsi/gomega"
 	promv1a1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 
@@ -45,6 +47,42 @@ func EnsureNoResources(k8sClient client.Client, ctx context.Context, list []clie
 	}, timeout, interval).Should(BeTrue())
 }
 
+// WaitForEvent waits for an event with the given reason to be recorded on the object
+func WaitForEvent(k8sClient client.Client, ctx context.Context, obj client.Object, reason string, timeout time.Duration, interval time.Duration) {
+	EventuallyWithOffset(1, func() bool {
+		return isEventRecorded(k8sClient, ctx, obj, reason, "")
+	}, timeout, interval).Should(BeTrue())
+}
+
+// WaitForEventWithMessage waits for an event with the given reason, whose message contains
+// the given substring, to be recorded on the object
+func WaitForEventWithMessage(k8sClient client.Client, ctx context.Context, obj client.Object, reason string, message string, timeout time.Duration, interval time.Duration) {
+	EventuallyWithOffset(1, func() bool {
+		return isEventRecorded(k8sClient, ctx, obj, reason, message)
+	}, timeout, interval).Should(BeTrue())
+}
+
+// EnsureNoEvent ensures that no event with the given reason is recorded on the object
+func EnsureNoEvent(k8sClient client.Client, ctx context.Context, obj client.Object, reason string, timeout time.Duration, interval time.Duration) {
+	ConsistentlyWithOffset(1, func() bool {
+		return isEventRecorded(k8sClient, ctx, obj, reason, "")
+	}, timeout, interval).Should(BeFalse())
+}
+
+func isEventRecorded(k8sClient client.Client, ctx context.Context, obj client.Object, reason string, message string) bool {
+	events := &corev1.EventList{}
+	if err := k8sClient.List(ctx, events, client.InNamespace(obj.GetNamespace())); err != nil {
+		return false
+	}
+	for i := range events.Items {
+		event := &events.Items[i]
+		if event.Reason == reason && event.InvolvedObject.Name == obj.GetName() && strings.Contains(event.Message, message) {
+			return true
+		}
+	}
+	return false
+}
+
 func GetResourceKey(obj client.Object) client.ObjectKey {
 	return client.ObjectKeyFromObject(obj)
 }
@@ -70,6 +108,21 @@ func ProbeReadiness() (int, error) {
 	return resp.StatusCode, nil
 }
 
+// ProbeReadinessServer sends a GET request for the given path, including any query, to the
+// readiness server and returns the status code and body of the response
+func ProbeReadinessServer(path string) (int, []byte, error) {
+	resp, err := http.Get("http://localhost:8081" + path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, body, nil
+}
+
 func ToJsonOrDie(value interface{}) []byte {
 	if bytes, err := json.Marshal(value); err == nil {
 		return bytes
@@ -0,0 +1,308 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Keys of the add-on parameters secret
+const (
+	StorageClusterSizeKey      = "size"
+	EnableMCGKey               = "enable-mcg"
+	StorageClassNameKey        = "storage-class"
+	OSDDeviceSizeKey           = "osd-device-size"
+	PortableKey                = "portable"
+	NotificationEmailKeyPrefix = "notification-email"
+	NotificationResolvedKey    = "notification-send-resolved"
+	NotificationDigestKey      = "notification-digest"
+	StorageProviderEndpointKey = "storage-provider-endpoint"
+	OnboardingTicketKey        = "onboarding-ticket"
+	ConsumerCIDRsKey           = "consumer-cidrs"
+)
+
+// MaxNotificationEmails is the maximal number of notification email recipients
+const MaxNotificationEmails = 10
+
+// Default values of the optional add-on parameters
+const (
+	DefaultEnableMCG     = false
+	DefaultOSDDeviceSize = "1Ti"
+	DefaultPortable      = true
+
+	DefaultNotificationResolved = false
+	DefaultNotificationDigest   = false
+)
+
+// AddonParams holds the typed values of the add-on parameters secret
+type AddonParams struct {
+	// StorageClusterSize is the requested storage device set count of converged and provider
+	// deployments. Consumer deployments use it as the capacity, in TiB, requested from the
+	// storage provider.
+	StorageClusterSize int
+
+	// EnableMCG indicates whether the Multi Cloud Gateway should be deployed
+	EnableMCG bool
+
+	// StorageClassName is the storage class used for the mon and OSD PVCs. An empty
+	// value means that the default storage class of the cloud provider should be used
+	StorageClassName string
+
+	// OSDDeviceSize is the size of a single OSD device
+	OSDDeviceSize resource.Quantity
+
+	// Portable indicates whether OSDs can move between nodes
+	Portable bool
+
+	// NotificationEmails are the valid and unique customer addresses that receive
+	// alert notifications, ordered by the index of their add-on parameter key
+	NotificationEmails []string
+
+	// NotificationResolved indicates whether customers are notified when an alert is resolved
+	NotificationResolved bool
+
+	// NotificationDigest indicates whether non-critical customer alerts are batched
+	// into a daily digest email
+	NotificationDigest bool
+
+	// StorageProviderEndpoint and OnboardingTicket are used by consumer deployments
+	// to connect to the storage provider
+	StorageProviderEndpoint string
+	OnboardingTicket        string
+
+	// ConsumerCIDRs are the networks from which consumer clusters reach the storage
+	// provider API server of a provider deployment
+	ConsumerCIDRs []string
+}
+
+// NewDefaultAddonParams returns add-on parameters set to their default values
+func NewDefaultAddonParams() *AddonParams {
+	return &AddonParams{
+		EnableMCG:            DefaultEnableMCG,
+		OSDDeviceSize:        resource.MustParse(DefaultOSDDeviceSize),
+		Portable:             DefaultPortable,
+		NotificationEmails:   []string{},
+		ConsumerCIDRs:        []string{},
+		NotificationResolved: DefaultNotificationResolved,
+		NotificationDigest:   DefaultNotificationDigest,
+	}
+}
+
+// GetNotificationEmailKey returns the add-on parameter key of the notification email at the given index
+func GetNotificationEmailKey(index int) string {
+	return fmt.Sprintf("%s-%d", NotificationEmailKeyPrefix, index)
+}
+
+// IsNotificationEmailKey returns true if the given add-on parameter key, or field error
+// path, refers to a notification email
+func IsNotificationEmailKey(key string) bool {
+	return strings.HasPrefix(key, NotificationEmailKeyPrefix+"-") || strings.HasPrefix(key, NotificationEmailKeyPrefix+"[")
+}
+
+// ParseAddonParams parses and validates the data of the add-on parameters secret. Missing
+// optional values are set to their defaults. The returned params are always usable: fields
+// with invalid values keep their defaults and are reported in the returned error list.
+func ParseAddonParams(data map[string][]byte) (*AddonParams, field.ErrorList) {
+	params := NewDefaultAddonParams()
+	errs := field.ErrorList{}
+
+	if value, found := data[StorageClusterSizeKey]; !found {
+		errs = append(errs, field.Required(field.NewPath(StorageClusterSizeKey), "storage cluster size must be set"))
+	} else if size, err := strconv.Atoi(string(value)); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath(StorageClusterSizeKey), string(value), "must be an integer"))
+	} else {
+		params.StorageClusterSize = size
+	}
+
+	if value, found := data[EnableMCGKey]; found {
+		if enable, err := strconv.ParseBool(string(value)); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(EnableMCGKey), string(value), "must be a boolean"))
+		} else {
+			params.EnableMCG = enable
+		}
+	}
+
+	params.StorageClassName = string(data[StorageClassNameKey])
+
+	if value, found := data[OSDDeviceSizeKey]; found {
+		if size, err := resource.ParseQuantity(string(value)); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(OSDDeviceSizeKey), string(value), "must be a quantity"))
+		} else {
+			params.OSDDeviceSize = size
+		}
+	}
+
+	if value, found := data[PortableKey]; found {
+		if portable, err := strconv.ParseBool(string(value)); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(PortableKey), string(value), "must be a boolean"))
+		} else {
+			params.Portable = portable
+		}
+	}
+
+	params.NotificationEmails, errs = parseNotificationEmails(data, errs)
+
+	if value, found := data[NotificationResolvedKey]; found {
+		if resolved, err := strconv.ParseBool(string(value)); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(NotificationResolvedKey), string(value), "must be a boolean"))
+		} else {
+			params.NotificationResolved = resolved
+		}
+	}
+
+	if value, found := data[NotificationDigestKey]; found {
+		if digest, err := strconv.ParseBool(string(value)); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(NotificationDigestKey), string(value), "must be a boolean"))
+		} else {
+			params.NotificationDigest = digest
+		}
+	}
+
+	params.StorageProviderEndpoint = string(data[StorageProviderEndpointKey])
+	params.OnboardingTicket = string(data[OnboardingTicketKey])
+
+	// Consumer CIDRs are given as a comma separated list
+	for _, cidr := range strings.Split(string(data[ConsumerCIDRsKey]), ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			params.ConsumerCIDRs = append(params.ConsumerCIDRs, cidr)
+		}
+	}
+
+	errs = append(errs, params.Validate()...)
+	return params, errs
+}
+
+// Validate checks the values of the add-on parameters and returns an error for each invalid field
+func (p *AddonParams) Validate() field.ErrorList {
+	errs := field.ErrorList{}
+
+	if p.StorageClusterSize < 0 {
+		errs = append(errs, field.Invalid(field.NewPath(StorageClusterSizeKey), p.StorageClusterSize, "must not be negative"))
+	}
+	if p.StorageClassName != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(p.StorageClassName) {
+			errs = append(errs, field.Invalid(field.NewPath(StorageClassNameKey), p.StorageClassName, msg))
+		}
+	}
+	if p.OSDDeviceSize.Sign() <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath(OSDDeviceSizeKey), p.OSDDeviceSize.String(), "must be positive"))
+	}
+	if len(p.NotificationEmails) > MaxNotificationEmails {
+		errs = append(errs, field.TooMany(field.NewPath(NotificationEmailKeyPrefix), len(p.NotificationEmails), MaxNotificationEmails))
+	}
+	for i, cidr := range p.ConsumerCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(ConsumerCIDRsKey).Index(i), cidr, "must be a valid CIDR"))
+		}
+	}
+	for i, email := range p.NotificationEmails {
+		// Only bare addresses are accepted, as the value is used as is in the alertmanager config
+		if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+			errs = append(errs, field.Invalid(field.NewPath(NotificationEmailKeyPrefix).Index(i), email, "must be a valid email address"))
+		}
+	}
+
+	return errs
+}
+
+// BlockingAddonParamKeys are the add-on parameters whose invalid values keep the operator from
+// reconciling the storage cluster or the network policies. Invalid values of the other add-on
+// parameters are left out, or replaced by their default value, by the operator.
+var BlockingAddonParamKeys = []string{
+	StorageClusterSizeKey,
+	EnableMCGKey,
+	StorageClassNameKey,
+	OSDDeviceSizeKey,
+	PortableKey,
+	ConsumerCIDRsKey,
+}
+
+// FilterAddonParamErrors returns the errors reported for any of the given add-on parameter
+// keys as a single error, or nil if there are none
+func FilterAddonParamErrors(errs field.ErrorList, keys ...string) error {
+	filtered := field.ErrorList{}
+	for _, err := range errs {
+		for _, key := range keys {
+			if err.Field == key || strings.HasPrefix(err.Field, key+"[") {
+				filtered = append(filtered, err)
+				break
+			}
+		}
+	}
+	return filtered.ToAggregate()
+}
+
+// parseNotificationEmails collects the notification email addresses from all the
+// notification-email-N keys, which are not required to be contiguous. Addresses are
+// validated according to RFC 5322 and deduplicated. Invalid addresses and addresses
+// beyond MaxNotificationEmails are left out and reported in the returned error list.
+func parseNotificationEmails(data map[string][]byte, errs field.ErrorList) ([]string, field.ErrorList) {
+	keys := []string{}
+	for key := range data {
+		if IsNotificationEmailKey(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	indices := []int{}
+	for _, key := range keys {
+		index, err := strconv.Atoi(strings.TrimPrefix(key, NotificationEmailKeyPrefix+"-"))
+		if err != nil || index < 0 || key != GetNotificationEmailKey(index) {
+			errs = append(errs, field.NotSupported(field.NewPath(key), key, []string{NotificationEmailKeyPrefix + "-<index>"}))
+			continue
+		}
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	emails := []string{}
+	seen := map[string]bool{}
+	for _, index := range indices {
+		key := GetNotificationEmailKey(index)
+		value := strings.TrimSpace(string(data[key]))
+		if value == "" {
+			continue
+		}
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			errs = append(errs, field.Invalid(field.NewPath(key), value, "must be a valid email address"))
+			continue
+		}
+		normalized := strings.ToLower(address.Address)
+		if seen[normalized] {
+			continue
+		}
+		if len(emails) == MaxNotificationEmails {
+			errs = append(errs, field.Forbidden(field.NewPath(key), fmt.Sprintf("exceeds the maximum of %d notification email recipients", MaxNotificationEmails)))
+			continue
+		}
+		seen[normalized] = true
+		emails = append(emails, address.Address)
+	}
+
+	return emails, errs
+}
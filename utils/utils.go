@@ -52,6 +52,16 @@ func AddLabel(obj metav1.Object, key string, value string) {
 	labels[key] = value
 }
 
+// AddAnnotation add an annotation to a resource metadata
+func AddAnnotation(obj metav1.Object, key string, value string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+		obj.SetAnnotations(annotations)
+	}
+	annotations[key] = value
+}
+
 // GetRegexMatcher converts list of alerts to regex matcher
 func GetRegexMatcher(alerts []string) string {
 	return "^" + strings.Join(alerts, "$|^") + "$"
@@ -0,0 +1,69 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// AddonParamsSecretValidatorPath is the path in which the add-on parameters secret validator is served
+const AddonParamsSecretValidatorPath = "/validate-addon-params-secret"
+
+// +kubebuilder:webhook:path=/validate-addon-params-secret,mutating=false,failurePolicy=ignore,sideEffects=None,groups="",resources=secrets,verbs=create;update,versions=v1,name=vaddonparams.ocs.openshift.io,admissionReviewVersions={v1,v1beta1}
+
+// AddonParamsSecretValidator rejects add-on parameters secrets with invalid values that the
+// operator cannot work around, such as an invalid storage cluster size. Invalid notification
+// parameters are admitted, the operator leaves them out and reports them. The webhook
+// is called for all the secrets in the operator namespace, as webhook object selectors cannot
+// match a name. Secrets other than the add-on parameters secret are always allowed.
+type AddonParamsSecretValidator struct {
+	SecretName      string
+	SecretNamespace string
+
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &AddonParamsSecretValidator{}
+var _ admission.DecoderInjector = &AddonParamsSecretValidator{}
+
+// Handle validates the values of the add-on parameters secret
+func (v *AddonParamsSecretValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Name != v.SecretName || req.Namespace != v.SecretNamespace {
+		return admission.Allowed("")
+	}
+
+	secret := &corev1.Secret{}
+	if err := v.decoder.Decode(req, secret); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	_, errs := utils.ParseAddonParams(secret.Data)
+	if err := utils.FilterAddonParamErrors(errs, utils.BlockingAddonParamKeys...); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder
+func (v *AddonParamsSecretValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,102 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/red-hat-storage/ocs-osd-deployer/utils"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Add-on parameters secret validator", func() {
+	const (
+		secretName      = "addon-test-parameters"
+		secretNamespace = "test-namespace"
+	)
+
+	var validator *AddonParamsSecretValidator
+
+	newRequest := func(name string, namespace string, data map[string][]byte) admission.Request {
+		secret := &corev1.Secret{}
+		secret.APIVersion = "v1"
+		secret.Kind = "Secret"
+		secret.Name = name
+		secret.Namespace = namespace
+		secret.Data = data
+		raw, err := json.Marshal(secret)
+		Expect(err).ToNot(HaveOccurred())
+		return admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Name:      name,
+				Namespace: namespace,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		validator = &AddonParamsSecretValidator{
+			SecretName:      secretName,
+			SecretNamespace: secretNamespace,
+		}
+		decoder, err := admission.NewDecoder(scheme.Scheme)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(validator.InjectDecoder(decoder)).Should(Succeed())
+	})
+
+	It("should allow valid add-on parameters", func() {
+		resp := validator.Handle(context.Background(), newRequest(secretName, secretNamespace, map[string][]byte{
+			utils.StorageClusterSizeKey: []byte("1"),
+			utils.EnableMCGKey:          []byte("true"),
+		}))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+	It("should deny add-on parameters without a storage cluster size", func() {
+		resp := validator.Handle(context.Background(), newRequest(secretName, secretNamespace, map[string][]byte{}))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(utils.StorageClusterSizeKey))
+	})
+	It("should deny add-on parameters with invalid values", func() {
+		resp := validator.Handle(context.Background(), newRequest(secretName, secretNamespace, map[string][]byte{
+			utils.StorageClusterSizeKey: []byte("1"),
+			utils.EnableMCGKey:          []byte("maybe"),
+			utils.ConsumerCIDRsKey:      []byte("10.0.0.0/33"),
+		}))
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(utils.EnableMCGKey))
+		Expect(string(resp.Result.Reason)).To(ContainSubstring(utils.ConsumerCIDRsKey))
+	})
+	It("should allow add-on parameters with rejected notification emails", func() {
+		data := map[string][]byte{
+			utils.StorageClusterSizeKey:      []byte("1"),
+			utils.GetNotificationEmailKey(0): []byte("not an email"),
+			utils.NotificationResolvedKey:    []byte("maybe"),
+		}
+		for i := 1; i <= utils.MaxNotificationEmails+1; i++ {
+			data[utils.GetNotificationEmailKey(i)] = []byte(fmt.Sprintf("user%d@example.com", i))
+		}
+		_, errs := utils.ParseAddonParams(data)
+		Expect(errs).ToNot(BeEmpty())
+
+		resp := validator.Handle(context.Background(), newRequest(secretName, secretNamespace, data))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+	It("should allow other secrets regardless of their content", func() {
+		resp := validator.Handle(context.Background(), newRequest("other-secret", secretNamespace, map[string][]byte{
+			utils.EnableMCGKey: []byte("maybe"),
+		}))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+	It("should allow a secret with the same name in another namespace", func() {
+		resp := validator.Handle(context.Background(), newRequest(secretName, "other-namespace", map[string][]byte{}))
+		Expect(resp.Allowed).To(BeTrue())
+	})
+})